@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/supermuesli/anim8/pkg/cli"
+)
+
+// anim8-export is the same as anim8 export, but builds without GLFW
+func main() {
+	err := cli.Export("anim8-export", os.Args[1:])
+	if err == flag.ErrHelp {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "anim8-export:", err)
+		os.Exit(1)
+	}
+}
@@ -1,31 +1,55 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
 	"github.com/faiface/pixel/pixelgl"
 	"github.com/kbinani/screenshot"
 
+	"github.com/supermuesli/anim8/pkg/assets"
+	"github.com/supermuesli/anim8/pkg/cli"
 	"github.com/supermuesli/anim8/pkg/render"
 )
 
-func run() {
+const usage = `usage:
+  anim8 [-size WIDTHxHEIGHT]             open the editor
+  anim8 export <project> [flags]         render a project without opening a window
+`
+
+// document resolution, independent of the display so that projects can be shared
+var size = flag.String("size", "1920x1080", "document resolution as WIDTHxHEIGHT, e.g. 512x512")
+
+// parseSize parses a resolution such as 1920x1080
+func parseSize(s string) (int, int, error) {
+	var width, height int
+	if _, err := fmt.Sscanf(strings.ToLower(s), "%dx%d", &width, &height); err != nil || width < 1 || height < 1 {
+		return 0, 0, fmt.Errorf("invalid size %q, expected WIDTHxHEIGHT", s)
+	}
+	return width, height, nil
+}
+
+func run(docWidth, docHeight int) {
 	// get display dimensions
 	bounds := screenshot.GetDisplayBounds(0)
 	width := float64(bounds.Dx())
 	height := float64(bounds.Dy())
 
 	// get bindata stuff
-	brush, err := Asset("data/brush.png")
+	brush, err := assets.Asset("data/brush.png")
 	if err != nil {
 		panic(err)
 	}
 
-	font, err := Asset("data/ka1.ttf")
+	font, err := assets.Asset("data/ka1.ttf")
 	if err != nil {
 		panic(err)
 	}
 
 	// initialize new canvas
-	canvas := render.NewCanvas(width, height, brush, font)
+	canvas := render.NewCanvas(width, height, docWidth, docHeight, brush, font)
 
 	// render loop
 	for !canvas.Win.Closed() {
@@ -37,6 +61,55 @@ func run() {
 	}
 }
 
+// command runs the subcommand given by `args` and returns the exit code. Subcommands never
+// touch the window, so they work without a display.
+func command(args []string) int {
+	var err error
+	switch args[0] {
+	case "export":
+		err = cli.Export("anim8 export", args[1:])
+	case "help":
+		fmt.Print(usage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "anim8: unknown command %q\n%s", args[0], usage)
+		return 2
+	}
+
+	if err == flag.ErrHelp {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "anim8:", err)
+		return 1
+	}
+	return 0
+}
+
 func main() {
-	pixelgl.Run(run)
+	// subcommands such as export run without a window
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		os.Exit(command(os.Args[1:]))
+	}
+
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// anything left over is a mistake, rather than a reason to open the window anyway
+	if flag.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "anim8: unexpected argument %q\n%s", flag.Arg(0), usage)
+		os.Exit(2)
+	}
+	docWidth, docHeight, err := parseSize(*size)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "anim8:", err)
+		os.Exit(2)
+	}
+
+	pixelgl.Run(func() {
+		run(docWidth, docHeight)
+	})
 }
\ No newline at end of file
@@ -0,0 +1,83 @@
+package brush
+
+import (
+	"github.com/supermuesli/anim8/pkg/scene"
+)
+
+// Brush describes how a stroke is stamped along the path of the pointer
+type Brush struct {
+	Name string
+
+	// Tip is the name of the tip image, "" is the default tip, see Tip
+	Tip string
+
+	// Size is the brush size a preset starts out with
+	Size float64
+
+	// Spacing between dabs as a fraction of the brush size
+	Spacing float64
+
+	// Opacity the stroke as a whole is painted with, overlapping dabs don't build up beyond it
+	Opacity float64
+
+	// Flow is the opacity of every single dab
+	Flow float64
+
+	// SizeJitter shrinks dabs randomly by up to this fraction of their size
+	SizeJitter float64
+
+	// AngleJitter rotates dabs randomly by up to this fraction of a half turn either way
+	AngleJitter float64
+
+	// Scatter moves dabs randomly across the stroke by up to this multiple of the brush size
+	Scatter float64
+
+	// FlowJitter lowers the flow of dabs randomly by up to this fraction
+	FlowJitter float64
+
+	// SpeedSize and SpeedFlow thin and fade the stroke by up to these fractions the faster the
+	// pointer moves, which stands in for the pressure a plain mouse doesn't have
+	SpeedSize float64
+	SpeedFlow float64
+
+	// TaperIn and TaperOut are how far the stroke grows at its start and fades at its end, in
+	// multiples of the brush size
+	TaperIn  float64
+	TaperOut float64
+}
+
+// Presets are the brushes to pick from, the first one is the default
+var Presets = []Brush{
+	{Name: "Default", Size: 1, Spacing: 0.05, Opacity: 1, Flow: 1},
+	{Name: "Pencil", Tip: "hard", Size: 1, Spacing: 0.15, Opacity: 1, Flow: 0.7, FlowJitter: 0.3, SpeedFlow: 0.5, TaperIn: 1, TaperOut: 1},
+	{Name: "Ink", Tip: "hard", Size: 2, Spacing: 0.05, Opacity: 1, Flow: 1, SpeedSize: 0.7, TaperIn: 2, TaperOut: 4},
+	{Name: "Marker", Tip: "hard", Size: 6, Spacing: 0.05, Opacity: 0.5, Flow: 1},
+	{Name: "Airbrush", Size: 12, Spacing: 0.05, Opacity: 1, Flow: 0.05, SpeedFlow: 0.8},
+	{Name: "Chalk", Tip: "grain", Size: 4, Spacing: 0.2, Opacity: 1, Flow: 0.8, SizeJitter: 0.3, AngleJitter: 1, Scatter: 0.1, FlowJitter: 0.4},
+	{Name: "Spray", Tip: "hard", Size: 1, Spacing: 0.5, Opacity: 1, Flow: 0.8, SizeJitter: 0.6, Scatter: 3},
+}
+
+// Steady returns the brush without simulated pressure, so that its strokes keep their width and
+// opacity from start to end
+func (b Brush) Steady() Brush {
+	b.SpeedSize, b.SpeedFlow = 0, 0
+	b.TaperIn, b.TaperOut = 0, 0
+	return b
+}
+
+// Of returns the brush that `s` is painted with
+func Of(s *scene.Stroke) Brush {
+	b := Presets[Preset(s.Brush)]
+	b.Tip = s.Tip
+	return b
+}
+
+// Preset returns the index of the preset named `name`, the default preset if there is none
+func Preset(name string) int {
+	for i, b := range Presets {
+		if b.Name == name {
+			return i
+		}
+	}
+	return 0
+}
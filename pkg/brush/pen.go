@@ -0,0 +1,103 @@
+package brush
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/supermuesli/anim8/pkg/scene"
+)
+
+// FullSpeed is how fast the pointer moves in pixels per second when the stroke is as thin and
+// faint as the speed makes it
+const FullSpeed = 2500.0
+
+// how thin and faint a stroke gets where it tapers
+const minTaper = 0.1
+
+// how far apart the points of a path are at least, in pixels
+const minGap = 2.0
+
+// Pen records the path of a stroke as the pointer moves, with the width and alpha that the
+// brush and its simulated pressure give every point
+type Pen struct {
+	brush Brush
+	size  float64
+	color color.RGBA
+
+	// how fast the pointer moves, from 0 to 1 at FullSpeed
+	speed float64
+
+	// how far the path went up to the last point
+	dist float64
+
+	last    scene.Point
+	started bool
+}
+
+// NewPen starts recording a stroke of `b` that is `size` pixels across and painted in `c`
+func NewPen(b Brush, size float64, c color.RGBA) *Pen {
+	return &Pen{brush: b, size: size, color: c}
+}
+
+// Speed tells how fast the pointer moves in pixels per second, which thins and fades the points
+// from now on as far as the brush says so
+func (p *Pen) Speed(v float64) {
+	// ease towards it, so that the width doesn't jump from one move to the next
+	p.speed += (math.Min(v/FullSpeed, 1) - p.speed) * 0.3
+}
+
+// To moves the pen to `x`, `y` and returns the point to add to the path, unless it is too close to
+// the last one to add anything
+func (p *Pen) To(x float64, y float64) (scene.Point, bool) {
+	if p.started {
+		dist := math.Hypot(x-p.last.X, y-p.last.Y)
+		if dist < minGap {
+			return scene.Point{}, false
+		}
+		p.dist += dist
+	}
+
+	b := p.brush
+	pt := scene.Point{
+		X:     x,
+		Y:     y,
+		Width: p.size * (1 - b.SpeedSize*p.speed),
+		Alpha: b.Flow * (1 - b.SpeedFlow*p.speed),
+		Color: p.color,
+	}
+	if b.TaperIn > 0 {
+		t := taper(p.dist / (b.TaperIn * p.size))
+		pt.Width *= t
+		pt.Alpha *= t
+	}
+
+	p.last, p.started = pt, true
+	return pt, true
+}
+
+// End tapers off the end of the path `points` that the pen recorded, now that it is known where
+// it is, and tells whether the brush does so at all
+func (p *Pen) End(points []scene.Point) bool {
+	if p.brush.TaperOut <= 0 {
+		return false
+	}
+
+	length := p.brush.TaperOut * p.size
+	dist := 0.0
+	for i := len(points) - 1; i >= 0 && dist < length; i-- {
+		t := taper(dist / length)
+		points[i].Width *= t
+		points[i].Alpha *= t
+
+		if i > 0 {
+			dist += math.Hypot(points[i].X-points[i-1].X, points[i].Y-points[i-1].Y)
+		}
+	}
+	return true
+}
+
+// taper eases from minTaper at `t` = 0 to 1 at `t` = 1 and beyond
+func taper(t float64) float64 {
+	t = math.Min(t, 1)
+	return minTaper + (1-minTaper)*t*(2-t)
+}
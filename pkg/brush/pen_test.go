@@ -0,0 +1,112 @@
+package brush
+
+import (
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/supermuesli/anim8/pkg/scene"
+)
+
+// record moves a pen of `b` along x at `speed`, one point every `gap` pixels, and tapers the end
+func record(b Brush, speed float64, gap float64, length float64) []scene.Point {
+	pen := NewPen(b, 10, color.RGBA{0, 0, 0, 255})
+
+	var points []scene.Point
+	for x := 0.0; x <= length; x += gap {
+		pen.Speed(speed)
+		if p, ok := pen.To(x, 0); ok {
+			points = append(points, p)
+		}
+	}
+	pen.End(points)
+	return points
+}
+
+func TestPenGap(t *testing.T) {
+	pen := NewPen(Presets[0], 10, color.RGBA{})
+	if _, ok := pen.To(0, 0); !ok {
+		t.Fatal("the first point was dropped")
+	}
+	if _, ok := pen.To(1, 1); ok {
+		t.Error("a point closer than the minimum gap was added")
+	}
+	if _, ok := pen.To(2, 2); !ok {
+		t.Error("a point far enough from the last one was dropped")
+	}
+}
+
+func TestPenSpeed(t *testing.T) {
+	tests := []struct {
+		name  string
+		brush Brush
+		speed float64
+		width float64
+		alpha float64
+	}{
+		{"resting ink", Presets[2].Steady(), 0, 10, 1},
+		{"fast ink", Presets[2], FullSpeed, 3, 1},
+		{"faster than full speed", Presets[2], 2 * FullSpeed, 3, 1},
+		{"fast pencil", Presets[1], FullSpeed, 10, 0.35},
+		{"fast default", Presets[0], FullSpeed, 10, 1},
+	}
+
+	for _, test := range tests {
+		b := test.brush
+		b.TaperIn, b.TaperOut = 0, 0
+
+		// the speed eases in, so the middle of a long stroke is at full speed
+		points := record(b, test.speed, 4, 400)
+		mid := points[len(points)/2]
+		if math.Abs(mid.Width-test.width) > 1e-6 || math.Abs(mid.Alpha-test.alpha) > 1e-6 {
+			t.Errorf("%s: point is %v across with alpha %v, want %v and %v", test.name, mid.Width, mid.Alpha, test.width, test.alpha)
+		}
+		if test.speed > 0 && points[0].Width < mid.Width {
+			t.Errorf("%s: the stroke starts thinner than it is at speed", test.name)
+		}
+	}
+}
+
+func TestPenTaper(t *testing.T) {
+	tests := []struct {
+		name  string
+		brush Brush
+		// how far from its start and end the stroke tapers, 0 for not at all
+		in  float64
+		out float64
+	}{
+		{"ink", Presets[2], 20, 40},
+		{"pencil", Presets[1], 10, 10},
+		{"steady", Presets[2].Steady(), 0, 0},
+		{"marker", Presets[3], 0, 0},
+	}
+
+	for _, test := range tests {
+		points := record(test.brush, 0, 2, 100)
+		end := points[len(points)-1]
+
+		for i, p := range points {
+			dist := math.Min(p.X, end.X-p.X)
+			tapered := p.X < test.in || end.X-p.X < test.out
+			if full := p.Width == 10; full == tapered {
+				t.Errorf("%s: point %d at %v from the nearest end is %v across", test.name, i, dist, p.Width)
+			}
+		}
+
+		// both ends taper off to the same thin tip, growing steadily towards the middle
+		if test.in > 0 {
+			if points[0].Width != 10*minTaper || end.Width != 10*minTaper {
+				t.Errorf("%s: the stroke starts %v and ends %v across, want %v", test.name, points[0].Width, end.Width, 10*minTaper)
+			}
+			for i := 1; points[i].X <= test.in; i++ {
+				if points[i].Width <= points[i-1].Width || points[i].Alpha <= points[i-1].Alpha {
+					t.Errorf("%s: the stroke doesn't grow from point %d to %d", test.name, i-1, i)
+				}
+			}
+		}
+	}
+
+	if NewPen(Presets[2].Steady(), 10, color.RGBA{}).End(nil) {
+		t.Error("a steady brush tapered the end")
+	}
+}
@@ -0,0 +1,168 @@
+package brush
+
+import (
+	"math"
+)
+
+// MaxStrength is the strongest a Stabilizer smooths
+const MaxStrength = 10
+
+// how far the pointer gets ahead of the stroke per step of strength, in pixels
+const ropeLength = 3.0
+
+// Point is a position on the path of a stroke
+type Point struct {
+	X float64
+	Y float64
+}
+
+// Stabilizer smooths the path of the pointer before it is stamped. The stroke hangs on a lazy
+// rope behind the pointer, so that it only follows deliberate moves, then the rope's positions
+// are averaged, and the averages connected by Catmull-Rom splines, so that sparse samples still
+// give a smooth curve instead of a polygon.
+type Stabilizer struct {
+	// Strength from 0, which follows the pointer as it is, to MaxStrength
+	Strength int
+
+	pointer Point
+	rope    Point
+	started bool
+
+	// the latest rope positions, which are averaged
+	recent []Point
+
+	// the latest averages, the spline between the two middle ones is drawn
+	samples []Point
+}
+
+// NewStabilizer starts smoothing a stroke at the given strength
+func NewStabilizer(strength int) *Stabilizer {
+	if strength < 0 {
+		strength = 0
+	}
+	if strength > MaxStrength {
+		strength = MaxStrength
+	}
+	return &Stabilizer{Strength: strength}
+}
+
+// Add moves the pointer to `x`, `y` and returns the points the stroke goes through by now
+func (s *Stabilizer) Add(x float64, y float64) []Point {
+	p := Point{x, y}
+	s.pointer = p
+	if s.Strength == 0 {
+		return []Point{p}
+	}
+
+	if !s.started {
+		s.started = true
+		s.rope = p
+		s.recent = []Point{p}
+		s.samples = []Point{p}
+		return []Point{p}
+	}
+
+	// the rope only pulls the stroke along once it is taut
+	dx, dy := p.X-s.rope.X, p.Y-s.rope.Y
+	if dist, length := math.Hypot(dx, dy), ropeLength*float64(s.Strength); dist > length {
+		s.rope.X += dx * (1 - length/dist)
+		s.rope.Y += dy * (1 - length/dist)
+	}
+
+	return s.follow()
+}
+
+// Finish pulls the stroke all the way to where the pointer was last and returns the points it
+// goes through on the way
+func (s *Stabilizer) Finish() []Point {
+	if s.Strength == 0 || !s.started {
+		return nil
+	}
+
+	// let go of the rope and let the average catch up with the pointer
+	s.rope = s.pointer
+	var end []Point
+	for i := 0; i < 1+2*s.Strength; i++ {
+		end = append(end, s.follow()...)
+	}
+
+	// the spline up to the last sample is still missing, which is its own successor
+	if n := len(s.samples); n >= 2 {
+		first := n - 3
+		if first < 0 {
+			first = 0
+		}
+		end = append(end, s.spline(s.samples[first], s.samples[n-2], s.samples[n-1], s.samples[n-1])...)
+	}
+	return end
+}
+
+// follow averages the latest rope positions and returns the spline up to the average before,
+// see sample
+func (s *Stabilizer) follow() []Point {
+	// the average keeps catching up while the pointer rests
+	s.recent = append(s.recent, s.rope)
+	if window := 1 + 2*s.Strength; len(s.recent) > window {
+		s.recent = s.recent[len(s.recent)-window:]
+	}
+
+	var avg Point
+	for _, r := range s.recent {
+		avg.X += r.X
+		avg.Y += r.Y
+	}
+	avg.X /= float64(len(s.recent))
+	avg.Y /= float64(len(s.recent))
+
+	return s.sample(avg)
+}
+
+// sample adds the smoothed position `p` and returns the spline up to the sample before it, which
+// is the last one that knows which way the path goes on
+func (s *Stabilizer) sample(p Point) []Point {
+	last := s.samples[len(s.samples)-1]
+
+	// samples that hardly moved just add kinks
+	if math.Hypot(p.X-last.X, p.Y-last.Y) < 1 {
+		return nil
+	}
+
+	s.samples = append(s.samples, p)
+	if len(s.samples) > 4 {
+		s.samples = s.samples[len(s.samples)-4:]
+	}
+
+	n := len(s.samples)
+	if n < 3 {
+		return nil
+	}
+	first := n - 4
+	if first < 0 {
+		first = 0
+	}
+	return s.spline(s.samples[first], s.samples[n-3], s.samples[n-2], s.samples[n-1])
+}
+
+// spline returns points along the Catmull-Rom spline from `p1` to `p2`, about a pixel apart
+// and without `p1`, bent by `p0` before and `p3` after it
+func (s *Stabilizer) spline(p0 Point, p1 Point, p2 Point, p3 Point) []Point {
+	n := int(math.Ceil(math.Hypot(p2.X-p1.X, p2.Y-p1.Y)))
+	if n < 1 {
+		n = 1
+	}
+
+	points := make([]Point, n)
+	for i := range points {
+		t := float64(i+1) / float64(n)
+		t2, t3 := t*t, t*t*t
+		points[i] = Point{
+			catmullRom(p0.X, p1.X, p2.X, p3.X, t, t2, t3),
+			catmullRom(p0.Y, p1.Y, p2.Y, p3.Y, t, t2, t3),
+		}
+	}
+	return points
+}
+
+func catmullRom(p0 float64, p1 float64, p2 float64, p3 float64, t float64, t2 float64, t3 float64) float64 {
+	return 0.5 * (2*p1 + (p2-p0)*t + (2*p0-5*p1+4*p2-p3)*t2 + (3*p1-p0-3*p2+p3)*t3)
+}
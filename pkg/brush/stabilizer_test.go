@@ -0,0 +1,132 @@
+package brush
+
+import (
+	"math"
+	"testing"
+)
+
+// stabilize feeds `path` to a stabilizer of `strength` and returns every point it gives, along
+// with how far behind the pointer the stroke was after each move
+func stabilize(strength int, path []Point) ([]Point, []float64) {
+	s := NewStabilizer(strength)
+
+	var out []Point
+	var lag []float64
+	for _, p := range path {
+		out = append(out, s.Add(p.X, p.Y)...)
+		last := out[len(out)-1]
+		lag = append(lag, math.Hypot(p.X-last.X, p.Y-last.Y))
+	}
+	return append(out, s.Finish()...), lag
+}
+
+// zigzag returns a path along x that wobbles `wobble` pixels up and down every step
+func zigzag(steps int, wobble float64) []Point {
+	path := make([]Point, steps)
+	for i := range path {
+		path[i] = Point{float64(4 * i), 50 + wobble*float64(1-2*(i%2))}
+	}
+	return path
+}
+
+func TestStabilizerOff(t *testing.T) {
+	path := zigzag(10, 5)
+	out, _ := stabilize(0, path)
+	if len(out) != len(path) {
+		t.Fatalf("got %d points, want the %d of the path", len(out), len(path))
+	}
+	for i := range path {
+		if out[i] != path[i] {
+			t.Errorf("point %d is %v, want %v", i, out[i], path[i])
+		}
+	}
+}
+
+func TestStabilizerRope(t *testing.T) {
+	// moves shorter than the rope don't pull the stroke along
+	s := NewStabilizer(5)
+	s.Add(100, 100)
+	for _, p := range []Point{{105, 100}, {95, 108}, {110, 95}, {100, 100}} {
+		if out := s.Add(p.X, p.Y); len(out) != 0 {
+			t.Errorf("wobbling to %v moved the stroke to %v", p, out)
+		}
+	}
+}
+
+func TestStabilizerSmoothing(t *testing.T) {
+	tests := []struct {
+		strength int
+		wobble   float64
+		maxLag   float64
+	}{
+		{1, 1, 15},
+		{3, 4, 30},
+		{MaxStrength, 8, 90},
+	}
+
+	for _, test := range tests {
+		path := zigzag(100, test.wobble)
+		out, lag := stabilize(test.strength, path)
+
+		// the stroke trails behind the pointer, but not too far
+		behind := 0
+		for i, l := range lag[1:] {
+			if l > test.maxLag {
+				t.Errorf("strength %d: the stroke is %v pixels behind after move %d", test.strength, l, i+1)
+				break
+			}
+			if l > 1 {
+				behind++
+			}
+		}
+		if behind < len(lag)/2 {
+			t.Errorf("strength %d: the stroke kept up with the pointer %d of %d moves", test.strength, len(lag)-behind, len(lag))
+		}
+
+		// and evens out its wobble
+		wobble := 0.0
+		for _, p := range out[len(out)/4 : 3*len(out)/4] {
+			wobble = math.Max(wobble, math.Abs(p.Y-50))
+		}
+		if wobble >= test.wobble/2 {
+			t.Errorf("strength %d: the stroke wobbles %v pixels, the pointer %v", test.strength, wobble, test.wobble)
+		}
+
+		// the curve has no gaps or kinks
+		for i := 1; i < len(out); i++ {
+			if d := math.Hypot(out[i].X-out[i-1].X, out[i].Y-out[i-1].Y); d > 1.5 {
+				t.Errorf("strength %d: points %d and %d are %v pixels apart", test.strength, i-1, i, d)
+				break
+			}
+		}
+
+		// finishing catches up with where the pointer was last
+		end, last := out[len(out)-1], path[len(path)-1]
+		if d := math.Hypot(end.X-last.X, end.Y-last.Y); d > 1 {
+			t.Errorf("strength %d: the stroke ends at %v, %v pixels from the pointer", test.strength, end, d)
+		}
+	}
+}
+
+func TestCatmullRom(t *testing.T) {
+	tests := []struct {
+		p    [4]float64
+		t    float64
+		want float64
+	}{
+		// the spline goes through the middle points
+		{[4]float64{0, 1, 5, 3}, 0, 1},
+		{[4]float64{0, 1, 5, 3}, 1, 5},
+		// evenly spaced points give a straight line
+		{[4]float64{0, 1, 2, 3}, 0.5, 1.5},
+		// and the outer points bend it
+		{[4]float64{0, 0, 1, 0}, 0.5, 0.5625},
+	}
+
+	for _, test := range tests {
+		p := test.p
+		if got := catmullRom(p[0], p[1], p[2], p[3], test.t, test.t*test.t, test.t*test.t*test.t); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("spline through %v at %v is %v, want %v", p, test.t, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package brush
+
+import (
+	"image/color"
+	"math"
+	"math/rand"
+
+	"github.com/supermuesli/anim8/pkg/scene"
+)
+
+// Dab is a single imprint of the brush tip
+type Dab struct {
+	X float64
+	Y float64
+
+	// Size the tip is stretched to, in pixels across
+	Size float64
+
+	// Angle the tip is rotated by in radians, clockwise in document coordinates
+	Angle float64
+
+	// Alpha the tip is painted with, from 0 to 1
+	Alpha float64
+
+	Color color.RGBA
+}
+
+// Stroker places dabs at even distances along the path of a stroke, no matter how far apart its
+// points are. The same points give the same dabs, whether they come one by one while the stroke
+// is painted or all at once when it is rendered again.
+type Stroker struct {
+	brush Brush
+	rand  *rand.Rand
+
+	last    scene.Point
+	started bool
+
+	// how far past the last point the next dab goes
+	next float64
+}
+
+// NewStroker starts rendering a stroke of `b`, with the jitter the `seed` gives
+func NewStroker(b Brush, seed int64) *Stroker {
+	return &Stroker{brush: b, rand: rand.New(rand.NewSource(seed))}
+}
+
+// Dabs renders the whole path `points` of a stroke of `b` into dabs, see Stroker
+func Dabs(b Brush, points []scene.Point, seed int64) []Dab {
+	s := NewStroker(b, seed)
+
+	var dabs []Dab
+	for _, p := range points {
+		dabs = append(dabs, s.To(p)...)
+	}
+	return dabs
+}
+
+// To continues the stroke to `p` and returns the dabs along the way. The first point gets a dab
+// of its own.
+func (s *Stroker) To(p scene.Point) []Dab {
+	if !s.started {
+		s.last, s.started = p, true
+		s.next = s.step(p.Width)
+		return []Dab{s.dab(p, 0, 0)}
+	}
+
+	from := s.last
+	dx, dy := p.X-from.X, p.Y-from.Y
+	dist := math.Hypot(dx, dy)
+	s.last = p
+	if dist == 0 {
+		return nil
+	}
+
+	var dabs []Dab
+	ux, uy := dx/dist, dy/dist
+	at := s.next
+	for at <= dist {
+		q := lerp(from, p, at/dist)
+		dabs = append(dabs, s.dab(q, ux, uy))
+		at += s.step(q.Width)
+	}
+	s.next = at - dist
+
+	return dabs
+}
+
+// step returns the distance to the next dab after one that is `size` pixels across. Dabs closer
+// than half a pixel don't add anything but work.
+func (s *Stroker) step(size float64) float64 {
+	return math.Max(s.brush.Spacing*size, 0.5)
+}
+
+// lerp returns the point `t` of the way from `a` to `b`
+func lerp(a scene.Point, b scene.Point, t float64) scene.Point {
+	mix := func(a float64, b float64) float64 {
+		return a + (b-a)*t
+	}
+	return scene.Point{
+		X:     mix(a.X, b.X),
+		Y:     mix(a.Y, b.Y),
+		Width: mix(a.Width, b.Width),
+		Alpha: mix(a.Alpha, b.Alpha),
+		Color: color.RGBA{
+			uint8(math.Round(mix(float64(a.Color.R), float64(b.Color.R)))),
+			uint8(math.Round(mix(float64(a.Color.G), float64(b.Color.G)))),
+			uint8(math.Round(mix(float64(a.Color.B), float64(b.Color.B)))),
+			uint8(math.Round(mix(float64(a.Color.A), float64(b.Color.A)))),
+		},
+	}
+}
+
+// dab applies the jitter of the brush to a dab at `p`, going in the direction `ux`, `uy`
+func (s *Stroker) dab(p scene.Point, ux float64, uy float64) Dab {
+	b := s.brush
+	d := Dab{X: p.X, Y: p.Y, Size: p.Width, Alpha: p.Alpha, Color: p.Color}
+
+	if b.SizeJitter > 0 {
+		d.Size *= 1 - s.rand.Float64()*b.SizeJitter
+	}
+	if b.AngleJitter > 0 {
+		d.Angle = (2*s.rand.Float64() - 1) * b.AngleJitter * math.Pi
+	}
+	if b.Scatter > 0 {
+		// scatter across the stroke, anywhere around the start where there is no direction yet
+		if ux == 0 && uy == 0 {
+			angle := s.rand.Float64() * 2 * math.Pi
+			ux, uy = math.Cos(angle), math.Sin(angle)
+		}
+		off := (2*s.rand.Float64() - 1) * b.Scatter * p.Width
+		d.X -= uy * off
+		d.Y += ux * off
+	}
+	if b.FlowJitter > 0 {
+		d.Alpha *= 1 - s.rand.Float64()*b.FlowJitter
+	}
+
+	return d
+}
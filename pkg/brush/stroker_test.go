@@ -0,0 +1,109 @@
+package brush
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/supermuesli/anim8/pkg/scene"
+)
+
+// line returns the points of a stroke along x, `gap` pixels apart and `width` pixels across
+func line(length float64, gap float64, width float64) []scene.Point {
+	var points []scene.Point
+	for x := 0.0; x <= length; x += gap {
+		points = append(points, scene.Point{X: x, Y: 10, Width: width, Alpha: 1})
+	}
+	return points
+}
+
+func TestStrokerSpacing(t *testing.T) {
+	tests := []struct {
+		name   string
+		brush  Brush
+		width  float64
+		gap    float64
+		spaced float64
+	}{
+		{"slow", Presets[0], 20, 0.25, 1},
+		{"fast", Presets[0], 20, 40, 1},
+		{"wide spacing", Presets[6], 4, 15, 2},
+		// dabs never get closer than half a pixel
+		{"thin", Presets[0], 1, 3, 0.5},
+	}
+
+	for _, test := range tests {
+		// no jitter, so that the dabs stay on the path
+		b := test.brush
+		b.SizeJitter, b.Scatter = 0, 0
+
+		dabs := Dabs(b, line(120, test.gap, test.width), 1)
+		if want := int(120/test.spaced) + 1; len(dabs) != want {
+			t.Errorf("%s: got %d dabs, want %d", test.name, len(dabs), want)
+		}
+		for i := 1; i < len(dabs); i++ {
+			if d := math.Hypot(dabs[i].X-dabs[i-1].X, dabs[i].Y-dabs[i-1].Y); math.Abs(d-test.spaced) > 1e-9 {
+				t.Errorf("%s: dabs %d and %d are %v apart, want %v", test.name, i-1, i, d, test.spaced)
+				break
+			}
+		}
+	}
+}
+
+func TestStrokerIncremental(t *testing.T) {
+	points := line(50, 7, 6)
+
+	s := NewStroker(Presets[5], 42)
+	var dabs []Dab
+	for _, p := range points {
+		dabs = append(dabs, s.To(p)...)
+	}
+
+	if !reflect.DeepEqual(dabs, Dabs(Presets[5], points, 42)) {
+		t.Error("painting point by point gives other dabs than rendering the stroke again")
+	}
+	if reflect.DeepEqual(dabs, Dabs(Presets[5], points, 43)) {
+		t.Error("another seed gives the same jitter")
+	}
+}
+
+func TestStrokerJitter(t *testing.T) {
+	tests := []struct {
+		name  string
+		brush Brush
+	}{
+		{"chalk", Presets[5]},
+		{"spray", Presets[6]},
+	}
+
+	for _, test := range tests {
+		b := test.brush
+		dabs := Dabs(b, line(200, 5, 10), 7)
+
+		jittered := false
+		for i, d := range dabs {
+			if d.Size > 10 || d.Size < 10*(1-b.SizeJitter) {
+				t.Errorf("%s: dab %d is %v pixels across", test.name, i, d.Size)
+			}
+			if math.Abs(d.Angle) > b.AngleJitter*math.Pi {
+				t.Errorf("%s: dab %d is rotated by %v", test.name, i, d.Angle)
+			}
+			if d.Alpha > 1 || d.Alpha < 1-b.FlowJitter {
+				t.Errorf("%s: dab %d has alpha %v", test.name, i, d.Alpha)
+			}
+			if off := math.Hypot(d.X-float64(i)*b.Spacing*10, d.Y-10); off > b.Scatter*10+1e-9 {
+				t.Errorf("%s: dab %d is scattered %v pixels", test.name, i, off)
+			}
+			// the first dab scatters in any direction, the others across the stroke only
+			if i > 0 && math.Abs(d.X-float64(i)*b.Spacing*10) > 1e-9 {
+				t.Errorf("%s: dab %d is scattered along the stroke to %v", test.name, i, d.X)
+			}
+			if d.Size != 10 || d.Y != 10 {
+				jittered = true
+			}
+		}
+		if !jittered {
+			t.Errorf("%s: no dab is jittered", test.name)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package brush
+
+import (
+	"image"
+	"math"
+)
+
+// tipSize is how many pixels across the built-in tips are
+const tipSize = 64
+
+// tips are the built-in tips besides the default one, made up rather than loaded so that they
+// come out the same everywhere
+var tips = map[string]func(x float64, y float64) float64{
+	// a disc with a crisp, antialiased edge
+	"hard": func(x float64, y float64) float64 {
+		return edge(math.Hypot(x, y))
+	},
+
+	// a disc riddled with holes, like chalk on a rough surface
+	"grain": func(x float64, y float64) float64 {
+		return edge(math.Hypot(x, y)) * math.Min(1, 2*noise(int(x*tipSize), int(y*tipSize)))
+	},
+}
+
+// Tip returns the built-in tip named `name` as a white image with its coverage in alpha, nil
+// if there is none
+func Tip(name string) *image.RGBA {
+	coverage, ok := tips[name]
+	if !ok {
+		return nil
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, tipSize, tipSize))
+	for y := 0; y < tipSize; y++ {
+		for x := 0; x < tipSize; x++ {
+			// from -1 to 1 across the tip, sampled at the pixel center
+			u := (float64(x)+0.5)/tipSize*2 - 1
+			v := (float64(y)+0.5)/tipSize*2 - 1
+
+			a := uint8(math.Round(255 * coverage(u, v)))
+			i := img.PixOffset(x, y)
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = a, a, a, a
+		}
+	}
+	return img
+}
+
+// edge fades out the last pixel before the distance `r` from the center reaches 1
+func edge(r float64) float64 {
+	return math.Max(0, math.Min(1, (1-r)*tipSize/2))
+}
+
+// noise hashes a pixel position into a coverage from 0 to 1
+func noise(x int, y int) float64 {
+	h := uint32(x)*374761393 + uint32(y)*668265263
+	h = (h ^ h>>13) * 1274126177
+	h ^= h >> 16
+	return float64(h&0xff) / 255
+}
+
+// Coverage turns `img` into a tip, white with its coverage in alpha. Images with transparency
+// keep their alpha, opaque ones cover where they are dark, like a stamp pressed on paper.
+func Coverage(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	tip := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	opaque := true
+	for y := bounds.Min.Y; y < bounds.Max.Y && opaque; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a < 0xffff {
+				opaque = false
+				break
+			}
+		}
+	}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			if opaque {
+				// luma of the premultiplied color, dark is covered
+				a = 0xffff - (299*r+587*g+114*b)/1000
+			}
+
+			c := uint8(a >> 8)
+			i := tip.PixOffset(x-bounds.Min.X, y-bounds.Min.Y)
+			tip.Pix[i], tip.Pix[i+1], tip.Pix[i+2], tip.Pix[i+3] = c, c, c, c
+		}
+	}
+	return tip
+}
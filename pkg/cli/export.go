@@ -0,0 +1,210 @@
+// Package cli runs the command line tools of anim8, which work without a window so that they
+// build and run without GLFW or a display, e.g. on a CI server.
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	_ "image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/supermuesli/anim8/pkg/assets"
+	"github.com/supermuesli/anim8/pkg/export"
+	"github.com/supermuesli/anim8/pkg/project"
+	"github.com/supermuesli/anim8/pkg/raster"
+	"github.com/supermuesli/anim8/pkg/scene"
+)
+
+const exportUsage = `usage: %s <project> [flags]
+
+Renders the frames of an anim8 project into image files without opening a window, e.g. to
+regenerate assets in a build pipeline.
+
+`
+
+// Export renders the frames of the project file named by `args` into one of the export formats,
+// as the command `command` with the flags in `args`. It returns flag.ErrHelp if the usage was
+// asked for.
+func Export(command string, args []string) error {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	format := flags.String("format", "png", "output format: png, gif, apng or sheet")
+	fps := flags.Int("fps", 0, "playback FPS, defaults to the FPS stored in the project")
+	out := flags.String("out", ".", "directory to write the output into")
+	name := flags.String("name", "", "name of the output files, defaults to the scene name")
+	background := flags.String("background", "", "background color as #rrggbb, #rrggbbaa or transparent, replacing the one of the project")
+	layers := flags.Bool("layers", false, "export every visible layer on its own, named after the layer")
+	once := flags.Bool("once", false, "play gif and apng animations a single time instead of looping forever")
+	dither := flags.Bool("dither", true, "dither gif colors, -dither=false maps every pixel to the nearest color")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), exportUsage, command)
+		flags.PrintDefaults()
+	}
+
+	paths, err := parse(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(paths) != 1 {
+		flags.Usage()
+		return errors.New("export: expected exactly one project file")
+	}
+
+	switch *format {
+	case "png", "gif", "apng", "sheet":
+	default:
+		return fmt.Errorf("export: unknown format %q", *format)
+	}
+
+	p, err := project.Load(paths[0])
+	if err != nil {
+		return err
+	}
+	s := p.Scene
+	if *fps > 0 {
+		s.FPS = *fps
+	}
+
+	// sprites are cut out along the content of the frames, which the default background would
+	// cover up, so they are only drawn on a background that was picked
+	if *format == "sheet" && s.Background.IsDefault() {
+		s.Background = scene.Background{}
+	}
+	if *background != "" {
+		c, err := scene.ParseColor(*background)
+		if err != nil {
+			return err
+		}
+		s.Background = scene.Background{Color: c}
+	}
+
+	if *name == "" {
+		*name = s.Name
+	}
+	if *name == "" {
+		*name = strings.TrimSuffix(filepath.Base(paths[0]), project.Extension)
+	}
+
+	tip, err := brushTip()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		return err
+	}
+
+	r := raster.New(tip)
+	r.SetTips(s)
+	animation := s.Animation()
+	opts := options{fps: s.FPS, holds: scene.Holds(animation), once: *once, dither: *dither}
+
+	if !*layers {
+		return write(*format, *out, *name, r.Frames(s, animation), opts)
+	}
+
+	for i, l := range s.Frame().Layers {
+		if l.Hidden {
+			continue
+		}
+
+		frames := r.Layers(animation, i, s.Width, s.Height)
+		if err := write(*format, *out, export.LayerName(*name, l.Name), frames, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// options are how the frames are played back, the formats use what applies to them
+type options struct {
+	fps int
+
+	// how many ticks every frame is held for
+	holds []int
+
+	// once plays animations a single time instead of looping them
+	once bool
+
+	// dither dithers the colors of GIFs
+	dither bool
+}
+
+// write exports `frames` in `format` into the directory `out`
+func write(format string, out string, name string, frames []*image.RGBA, opts options) error {
+	switch format {
+	case "png":
+		// a PNG per tick, so that the frames play at the right speed
+		return export.PNGs(out, name, export.Held(frames, opts.holds))
+	case "gif":
+		return writeFile(filepath.Join(out, name+".gif"), func(w io.Writer) error {
+			return export.GIF(w, frames, export.GIFOptions{
+				FPS:    opts.fps,
+				Holds:  opts.holds,
+				Once:   opts.once,
+				Dither: opts.dither,
+			})
+		})
+	case "apng":
+		return writeFile(filepath.Join(out, name+".png"), func(w io.Writer) error {
+			return export.APNG(w, frames, export.APNGOptions{FPS: opts.fps, Holds: opts.holds, Once: opts.once})
+		})
+	case "sheet":
+		return export.WriteSpriteSheet(out, frames, export.SheetOptions{
+			Name:    name,
+			FPS:     opts.fps,
+			Holds:   opts.holds,
+			Trim:    true,
+			Padding: 1,
+		})
+	}
+
+	return fmt.Errorf("export: unknown format %q", format)
+}
+
+// parse parses `args` with `flags`, allowing flags to come after positional arguments as well
+func parse(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+// brushTip decodes the brush the editor paints with, so exports look like the editor
+func brushTip() (image.Image, error) {
+	data, err := assets.Asset("data/brush.png")
+	if err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// writeFile creates the file at `path` and lets `encode` write its content
+func writeFile(path string, encode func(io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := encode(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
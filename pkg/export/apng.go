@@ -0,0 +1,267 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"io"
+)
+
+// APNGOptions configures the animated PNG exporter
+type APNGOptions struct {
+	// FPS is the playback speed, every frame is shown for 1/FPS seconds times its hold
+	FPS int
+	// Holds optionally holds each frame for several ticks, missing or non-positive holds count as 1
+	Holds []int
+	// Once plays the animation a single time instead of looping forever
+	Once bool
+}
+
+const (
+	apngDisposeNone = 0
+	apngBlendSource = 0
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// APNG encodes `frames` as a single lossless animated PNG with full alpha. Only the region of a
+// frame that differs from the previous frame is stored.
+func APNG(w io.Writer, frames []*image.RGBA, opts APNGOptions) error {
+	if len(frames) == 0 {
+		return errors.New("export: no frames to encode")
+	}
+	if opts.FPS < 1 {
+		opts.FPS = 1
+	}
+
+	size := frames[0].Bounds().Size()
+	for _, frame := range frames {
+		if frame.Bounds().Size() != size {
+			return errors.New("export: frames differ in size")
+		}
+	}
+
+	enc := &apngEncoder{w: bufio.NewWriter(w)}
+	enc.write(pngSignature)
+
+	// 8 bit truecolor with alpha for every frame
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], uint32(size.X))
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(size.Y))
+	ihdr[8] = 8
+	ihdr[9] = 6
+	enc.chunk("IHDR", ihdr)
+
+	plays := 0
+	if opts.Once {
+		plays = 1
+	}
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl[0:], uint32(len(frames)))
+	binary.BigEndian.PutUint32(actl[4:], uint32(plays))
+	enc.chunk("acTL", actl)
+
+	for i, frame := range frames {
+		// the first frame doubles as the default image and has to cover the whole canvas
+		region := image.Rect(0, 0, size.X, size.Y)
+		if i > 0 {
+			region = changed(frames[i-1], frame)
+		}
+
+		hold := 1
+		if i < len(opts.Holds) && opts.Holds[i] > 0 {
+			hold = opts.Holds[i]
+		}
+
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:], enc.nextSeq())
+		binary.BigEndian.PutUint32(fctl[4:], uint32(region.Dx()))
+		binary.BigEndian.PutUint32(fctl[8:], uint32(region.Dy()))
+		binary.BigEndian.PutUint32(fctl[12:], uint32(region.Min.X))
+		binary.BigEndian.PutUint32(fctl[16:], uint32(region.Min.Y))
+		binary.BigEndian.PutUint16(fctl[20:], uint16(hold))
+		binary.BigEndian.PutUint16(fctl[22:], uint16(opts.FPS))
+		fctl[24] = apngDisposeNone
+		fctl[25] = apngBlendSource
+		enc.chunk("fcTL", fctl)
+
+		data, err := compressRegion(frame, region.Add(frame.Bounds().Min))
+		if err != nil {
+			return err
+		}
+
+		if i == 0 {
+			enc.chunk("IDAT", data)
+		} else {
+			seq := make([]byte, 4, 4+len(data))
+			binary.BigEndian.PutUint32(seq, enc.nextSeq())
+			enc.chunk("fdAT", append(seq, data...))
+		}
+	}
+
+	enc.chunk("IEND", nil)
+
+	if enc.err != nil {
+		return enc.err
+	}
+	return enc.w.Flush()
+}
+
+// changed returns the smallest rectangle, relative to the frame origin, in which `cur` differs from `prev`
+func changed(prev *image.RGBA, cur *image.RGBA) image.Rectangle {
+	bounds := cur.Bounds()
+	offset := prev.Bounds().Min.Sub(bounds.Min)
+	region := image.Rectangle{}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if cur.RGBAAt(x, y) != prev.RGBAAt(x+offset.X, y+offset.Y) {
+				region = region.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+
+	if region.Empty() {
+		// frames can't be empty, so repeat a single pixel
+		return image.Rect(0, 0, 1, 1)
+	}
+	return region.Sub(bounds.Min)
+}
+
+// compressRegion returns the zlib compressed, filtered, non-premultiplied scanlines of `region`
+func compressRegion(img *image.RGBA, region image.Rectangle) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+
+	stride := 4 * region.Dx()
+	prev := make([]byte, stride)
+	cur := make([]byte, stride)
+	filtered := make([]byte, 1+stride)
+
+	for y := region.Min.Y; y < region.Max.Y; y++ {
+		for x := region.Min.X; x < region.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.RGBAAt(x, y)).(color.NRGBA)
+			i := 4 * (x - region.Min.X)
+			cur[i], cur[i+1], cur[i+2], cur[i+3] = c.R, c.G, c.B, c.A
+		}
+
+		filterRow(filtered, cur, prev)
+		if _, err := zw.Write(filtered); err != nil {
+			return nil, err
+		}
+		prev, cur = cur, prev
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// filterRow picks the PNG filter with the smallest sum of absolute differences for `cur`
+func filterRow(dst []byte, cur []byte, prev []byte) {
+	const bpp = 4
+
+	bestSum := -1
+	row := make([]byte, len(cur))
+
+	for f := byte(0); f < 5; f++ {
+		sum := 0
+		for i := range cur {
+			var a, b, c byte
+			if i >= bpp {
+				a = cur[i-bpp]
+				c = prev[i-bpp]
+			}
+			b = prev[i]
+
+			var v byte
+			switch f {
+			case 0:
+				v = cur[i]
+			case 1:
+				v = cur[i] - a
+			case 2:
+				v = cur[i] - b
+			case 3:
+				v = cur[i] - byte((int(a)+int(b))/2)
+			case 4:
+				v = cur[i] - paeth(a, b, c)
+			}
+
+			row[i] = v
+			if v < 128 {
+				sum += int(v)
+			} else {
+				sum += 256 - int(v)
+			}
+		}
+
+		if bestSum < 0 || sum < bestSum {
+			bestSum = sum
+			dst[0] = f
+			copy(dst[1:], row)
+		}
+	}
+}
+
+func paeth(a byte, b byte, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	if pa <= pb && pa <= pc {
+		return a
+	}
+	if pb <= pc {
+		return b
+	}
+	return c
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// apngEncoder writes PNG chunks and keeps track of the APNG sequence numbers
+type apngEncoder struct {
+	w   *bufio.Writer
+	seq uint32
+	err error
+}
+
+func (enc *apngEncoder) nextSeq() uint32 {
+	seq := enc.seq
+	enc.seq++
+	return seq
+}
+
+func (enc *apngEncoder) write(b []byte) {
+	if enc.err != nil {
+		return
+	}
+	_, enc.err = enc.w.Write(b)
+}
+
+func (enc *apngEncoder) chunk(name string, data []byte) {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header[0:], uint32(len(data)))
+	copy(header[4:], name)
+
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+
+	footer := make([]byte, 4)
+	binary.BigEndian.PutUint32(footer, crc.Sum32())
+
+	enc.write(header)
+	enc.write(data)
+	enc.write(footer)
+}
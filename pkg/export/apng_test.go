@@ -0,0 +1,149 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"image/png"
+	"testing"
+)
+
+// chunk is a PNG chunk read back from an encoded file
+type chunk struct {
+	name string
+	data []byte
+}
+
+// chunks splits the PNG `b` into its chunks, checking their CRCs
+func chunks(t *testing.T, b []byte) []chunk {
+	if !bytes.HasPrefix(b, pngSignature) {
+		t.Fatal("missing PNG signature")
+	}
+	b = b[len(pngSignature):]
+
+	var cs []chunk
+	for len(b) > 0 {
+		n := binary.BigEndian.Uint32(b)
+		c := chunk{string(b[4:8]), b[8 : 8+n]}
+		if crc32.ChecksumIEEE(b[4:8+n]) != binary.BigEndian.Uint32(b[8+n:]) {
+			t.Fatalf("%s chunk has a bad CRC", c.name)
+		}
+		cs = append(cs, c)
+		b = b[12+n:]
+	}
+	return cs
+}
+
+// decodeAPNG plays the APNG `b` back into full frames along with their delays as num/den,
+// the only dispose and blend operations the exporter uses are none and source
+func decodeAPNG(t *testing.T, b []byte) ([]*image.RGBA, [][2]uint16, uint32) {
+	var ihdr []byte
+	var plays uint32
+	var canvas *image.RGBA
+	var imgs []*image.RGBA
+	var delays [][2]uint16
+	var region image.Rectangle
+
+	// every frame is decoded on its own as a PNG of the size of its region
+	frame := func(data []byte) {
+		header := append([]byte(nil), ihdr...)
+		binary.BigEndian.PutUint32(header[0:], uint32(region.Dx()))
+		binary.BigEndian.PutUint32(header[4:], uint32(region.Dy()))
+
+		var buf bytes.Buffer
+		buf.Write(pngSignature)
+		for _, c := range []chunk{{"IHDR", header}, {"IDAT", data}, {"IEND", nil}} {
+			buf.Write(rawChunk(c.name, c.data))
+		}
+
+		img, err := png.Decode(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		draw.Draw(canvas, region, img, image.ZP, draw.Src)
+		imgs = append(imgs, image.NewRGBA(canvas.Rect))
+		copy(imgs[len(imgs)-1].Pix, canvas.Pix)
+	}
+
+	for _, c := range chunks(t, b) {
+		switch c.name {
+		case "IHDR":
+			ihdr = c.data
+			canvas = image.NewRGBA(image.Rect(0, 0, int(binary.BigEndian.Uint32(c.data)), int(binary.BigEndian.Uint32(c.data[4:]))))
+		case "acTL":
+			plays = binary.BigEndian.Uint32(c.data[4:])
+		case "fcTL":
+			x, y := int(binary.BigEndian.Uint32(c.data[12:])), int(binary.BigEndian.Uint32(c.data[16:]))
+			w, h := int(binary.BigEndian.Uint32(c.data[4:])), int(binary.BigEndian.Uint32(c.data[8:]))
+			region = image.Rect(x, y, x+w, y+h)
+			delays = append(delays, [2]uint16{binary.BigEndian.Uint16(c.data[20:]), binary.BigEndian.Uint16(c.data[22:])})
+		case "IDAT":
+			frame(c.data)
+		case "fdAT":
+			frame(c.data[4:])
+		}
+	}
+	return imgs, delays, plays
+}
+
+// rawChunk encodes a chunk the way apngEncoder.chunk does
+func rawChunk(name string, data []byte) []byte {
+	var buf bytes.Buffer
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(len(data)))
+	copy(header[4:], name)
+	buf.Write(header)
+	buf.Write(data)
+
+	footer := make([]byte, 4)
+	binary.BigEndian.PutUint32(footer, crc32.ChecksumIEEE(append(header[4:], data...)))
+	buf.Write(footer)
+	return buf.Bytes()
+}
+
+func TestAPNG(t *testing.T) {
+	in := frames(4)
+
+	var buf bytes.Buffer
+	if err := APNG(&buf, in, APNGOptions{FPS: 12, Holds: []int{1, 3}, Once: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// viewers without APNG support show the first frame
+	first, err := png.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.(*image.NRGBA).Pix, nrgba(in[0]).Pix) {
+		t.Error("default image differs from the first frame")
+	}
+
+	out, delays, plays := decodeAPNG(t, buf.Bytes())
+	if plays != 1 {
+		t.Errorf("plays %d times, want once", plays)
+	}
+	if len(out) != len(in) {
+		t.Fatalf("got %d frames, want %d", len(out), len(in))
+	}
+	for i := range in {
+		if !bytes.Equal(out[i].Pix, in[i].Pix) {
+			t.Errorf("frame %d differs", i)
+		}
+	}
+
+	want := [][2]uint16{{1, 12}, {3, 12}, {1, 12}, {1, 12}}
+	for i := range want {
+		if delays[i] != want[i] {
+			t.Errorf("delays are %v, want %v", delays, want)
+			break
+		}
+	}
+}
+
+func nrgba(img image.Image) *image.NRGBA {
+	dst := image.NewNRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), img, image.ZP, draw.Src)
+	return dst
+}
@@ -0,0 +1,110 @@
+package export
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+)
+
+// GIFOptions configures the animated GIF exporter
+type GIFOptions struct {
+	// FPS is the playback speed, every frame is shown for 1/FPS seconds times its hold
+	FPS int
+	// Holds optionally holds each frame for several ticks, missing or non-positive holds count as 1
+	Holds []int
+	// Once plays the animation a single time instead of looping forever
+	Once bool
+	// Dither spreads the quantization error with Floyd-Steinberg dithering
+	Dither bool
+	// Colors is the size of the generated palette including the transparent color, at most 256
+	Colors int
+}
+
+// GIF encodes `frames` as an animated GIF with a palette shared by all frames
+func GIF(w io.Writer, frames []*image.RGBA, opts GIFOptions) error {
+	if len(frames) == 0 {
+		return errors.New("export: no frames to encode")
+	}
+
+	if opts.FPS < 1 {
+		opts.FPS = 1
+	}
+	if opts.Colors < 2 || opts.Colors > 256 {
+		opts.Colors = 256
+	}
+
+	// index 0 is reserved for fully transparent pixels
+	palette := append(color.Palette{color.RGBA{}}, Quantize(frames, opts.Colors-1)...)
+
+	anim := &gif.GIF{
+		Image:    make([]*image.Paletted, len(frames)),
+		Delay:    make([]int, len(frames)),
+		Disposal: make([]byte, len(frames)),
+	}
+
+	if opts.Once {
+		anim.LoopCount = -1
+	}
+
+	// GIF delays are in 100ths of a second, which most frame durations don't divide into. The
+	// ticks shown so far are kept count of, so that the delays round up and down in turns and add
+	// up to the duration of the animation instead of drifting away from it.
+	ticks, elapsed := 0, 0
+	for i, frame := range frames {
+		anim.Image[i] = paletted(frame, palette, opts.Dither)
+		hold := 1
+		if i < len(opts.Holds) && opts.Holds[i] > 0 {
+			hold = opts.Holds[i]
+		}
+
+		ticks += hold
+		anim.Delay[i] = (200*ticks+opts.FPS)/(2*opts.FPS) - elapsed
+		if anim.Delay[i] < 2 {
+			// most viewers treat anything faster as 10
+			anim.Delay[i] = 2
+		}
+		elapsed += anim.Delay[i]
+		anim.Disposal[i] = gif.DisposalBackground
+	}
+
+	return gif.EncodeAll(w, anim)
+}
+
+// paletted maps `img` onto `palette`, keeping index 0 for pixels that are mostly transparent
+func paletted(img *image.RGBA, palette color.Palette, dither bool) *image.Paletted {
+	bounds := img.Bounds()
+	dst := image.NewPaletted(bounds, palette)
+
+	// opaque copy, so that the drawers below never pick the transparent color
+	opaque := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			if c.A == 0 {
+				continue
+			}
+			opaque.SetRGBA(x, y, unpremultiply(c))
+		}
+	}
+
+	var drawer draw.Drawer = draw.Src
+	if dither {
+		drawer = draw.FloydSteinberg
+	}
+	// draw into a paletted image without the transparent color, then shift the indices by one
+	tmp := image.NewPaletted(bounds, palette[1:])
+	drawer.Draw(tmp, bounds, opaque, bounds.Min)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if img.RGBAAt(x, y).A >= 128 {
+				dst.SetColorIndex(x, y, tmp.ColorIndexAt(x, y)+1)
+			}
+		}
+	}
+
+	return dst
+}
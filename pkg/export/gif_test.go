@@ -0,0 +1,82 @@
+package export
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+// frames returns `n` frames of a red square moving right on a transparent background
+func frames(n int) []*image.RGBA {
+	imgs := make([]*image.RGBA, n)
+	for i := range imgs {
+		imgs[i] = image.NewRGBA(image.Rect(0, 0, 16, 8))
+		for y := 2; y < 6; y++ {
+			for x := 2 * i; x < 2*i+4; x++ {
+				imgs[i].SetRGBA(x, y, color.RGBA{255, 0, 0, 255})
+			}
+		}
+	}
+	return imgs
+}
+
+func TestGIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := GIF(&buf, frames(3), GIFOptions{FPS: 15}); err != nil {
+		t.Fatal(err)
+	}
+
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 3 || anim.LoopCount != 0 {
+		t.Fatalf("got %d frames looping %d times, want 3 looping forever", len(anim.Image), anim.LoopCount)
+	}
+
+	img := anim.Image[1]
+	if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+		t.Error("transparent pixel is opaque")
+	}
+	if r, g, b, a := img.At(3, 3).RGBA(); r>>8 != 255 || g != 0 || b != 0 || a>>8 != 255 {
+		t.Errorf("red pixel is %v", img.At(3, 3))
+	}
+}
+
+func TestGIFDelays(t *testing.T) {
+	tests := []struct {
+		fps   int
+		holds []int
+		want  []int
+	}{
+		// 6.67 hundredths of a second per frame, rounded in turns
+		{15, nil, []int{7, 6, 7}},
+		{15, []int{1, 2, 1}, []int{7, 13, 7}},
+		{10, []int{3}, []int{30, 10, 10}},
+		// too fast for most viewers
+		{100, nil, []int{2, 2, 2}},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := GIF(&buf, frames(3), GIFOptions{FPS: test.fps, Holds: test.holds, Once: true}); err != nil {
+			t.Fatal(err)
+		}
+		anim, err := gif.DecodeAll(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if anim.LoopCount != -1 {
+			t.Errorf("loops %d times, want once", anim.LoopCount)
+		}
+		for i, d := range anim.Delay {
+			if d != test.want[i] {
+				t.Errorf("%d FPS with holds %v: delays are %v, want %v", test.fps, test.holds, anim.Delay, test.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// PNGs writes every frame as a PNG named `name` followed by the frame number into `dir`
+func PNGs(dir string, name string, frames []*image.RGBA) error {
+	if len(frames) == 0 {
+		return errors.New("export: no frames to encode")
+	}
+
+	for i, img := range frames {
+		path := filepath.Join(dir, fmt.Sprintf("%s%06d.png", name, i))
+		if err := writePNG(path, img); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Held repeats every frame as many times as it is held for, for formats without frame durations
+// such as PNGs. Missing or non-positive holds count as 1.
+func Held(frames []*image.RGBA, holds []int) []*image.RGBA {
+	var held []*image.RGBA
+	for i, img := range frames {
+		n := 1
+		if i < len(holds) && holds[i] > 0 {
+			n = holds[i]
+		}
+		for j := 0; j < n; j++ {
+			held = append(held, img)
+		}
+	}
+	return held
+}
+
+// LayerName returns the name to export the layer `layer` of the animation `name` under, when
+// layers are exported separately
+func LayerName(name string, layer string) string {
+	layer = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return '-'
+	}, strings.TrimSpace(layer))
+
+	return name + "-" + layer
+}
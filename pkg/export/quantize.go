@@ -0,0 +1,140 @@
+package export
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+// colorBox is a box in the 5 bit per channel RGB cube used for median cut quantization
+type colorBox struct {
+	entries []colorEntry
+}
+
+type colorEntry struct {
+	rgb   [3]uint8
+	count int
+}
+
+// Quantize picks at most `n` colors that represent the opaque pixels of all `imgs` using median cut
+func Quantize(imgs []*image.RGBA, n int) color.Palette {
+	// histogram of 5 bit per channel colors, which is plenty for a 256 color palette
+	var hist [1 << 15]int
+	for _, img := range imgs {
+		bounds := img.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				c := img.RGBAAt(x, y)
+				if c.A < 128 {
+					continue
+				}
+				c = unpremultiply(c)
+				hist[int(c.R>>3)<<10|int(c.G>>3)<<5|int(c.B>>3)]++
+			}
+		}
+	}
+
+	all := colorBox{}
+	for key, count := range hist {
+		if count > 0 {
+			all.entries = append(all.entries, colorEntry{[3]uint8{uint8(key >> 10), uint8(key >> 5 & 31), uint8(key & 31)}, count})
+		}
+	}
+
+	if len(all.entries) == 0 {
+		return color.Palette{color.RGBA{0, 0, 0, 255}}
+	}
+
+	boxes := []colorBox{all}
+	for len(boxes) < n {
+		// split the box with the widest channel range
+		widest, axis, span := -1, 0, 0
+		for i, box := range boxes {
+			if len(box.entries) < 2 {
+				continue
+			}
+			a, s := box.longestAxis()
+			if s > span {
+				widest, axis, span = i, a, s
+			}
+		}
+		if widest < 0 {
+			break
+		}
+
+		lo, hi := boxes[widest].split(axis)
+		boxes[widest] = lo
+		boxes = append(boxes, hi)
+	}
+
+	palette := make(color.Palette, len(boxes))
+	for i, box := range boxes {
+		palette[i] = box.average()
+	}
+	return palette
+}
+
+func (box colorBox) longestAxis() (int, int) {
+	axis, span := 0, -1
+	for a := 0; a < 3; a++ {
+		min, max := uint8(31), uint8(0)
+		for _, e := range box.entries {
+			if e.rgb[a] < min {
+				min = e.rgb[a]
+			}
+			if e.rgb[a] > max {
+				max = e.rgb[a]
+			}
+		}
+		if int(max-min) > span {
+			axis, span = a, int(max-min)
+		}
+	}
+	return axis, span
+}
+
+// split cuts the box in two at the pixel weighted median along `axis`
+func (box colorBox) split(axis int) (colorBox, colorBox) {
+	sort.Slice(box.entries, func(i, j int) bool {
+		return box.entries[i].rgb[axis] < box.entries[j].rgb[axis]
+	})
+
+	total := 0
+	for _, e := range box.entries {
+		total += e.count
+	}
+
+	median, sum := 1, 0
+	for i, e := range box.entries[:len(box.entries)-1] {
+		sum += e.count
+		median = i + 1
+		if 2*sum >= total {
+			break
+		}
+	}
+
+	return colorBox{box.entries[:median]}, colorBox{box.entries[median:]}
+}
+
+func (box colorBox) average() color.RGBA {
+	var r, g, b, total int
+	for _, e := range box.entries {
+		r += int(e.rgb[0]) * e.count
+		g += int(e.rgb[1]) * e.count
+		b += int(e.rgb[2]) * e.count
+		total += e.count
+	}
+
+	// expand 5 bit channels back to 8 bit
+	expand := func(v int) uint8 {
+		v = (v + total/2) / total
+		return uint8(v<<3 | v>>2)
+	}
+	return color.RGBA{expand(r), expand(g), expand(b), 255}
+}
+
+// unpremultiply turns an alpha-premultiplied color into an opaque color with the same hue
+func unpremultiply(c color.RGBA) color.RGBA {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return color.RGBA{n.R, n.G, n.B, 255}
+}
@@ -0,0 +1,293 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// SheetOptions configures the sprite sheet exporter
+type SheetOptions struct {
+	// Name is the animation name, it also names the atlas images
+	Name string
+	// FPS is the playback speed used for the frame durations
+	FPS int
+	// Holds optionally holds each frame for several ticks, missing or non-positive holds count as 1
+	Holds []int
+	// Trim crops every frame to the bounds of its visible content
+	Trim bool
+	// Padding is the amount of empty pixels between frames
+	Padding int
+	// MaxSize limits the width and height of an atlas image, frames that don't fit go into another one
+	MaxSize int
+}
+
+// SheetRect is a rectangle in the atlas JSON
+type SheetRect struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// SheetSize is a size in the atlas JSON
+type SheetSize struct {
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// SheetFrame describes where a frame ended up in the atlas image
+type SheetFrame struct {
+	Filename         string    `json:"filename"`
+	Frame            SheetRect `json:"frame"`
+	Rotated          bool      `json:"rotated"`
+	Trimmed          bool      `json:"trimmed"`
+	SpriteSourceSize SheetRect `json:"spriteSourceSize"`
+	SourceSize       SheetSize `json:"sourceSize"`
+	Duration         int       `json:"duration"`
+}
+
+// SheetTag names a range of frames in the atlas
+type SheetTag struct {
+	Name      string `json:"name"`
+	From      int    `json:"from"`
+	To        int    `json:"to"`
+	Direction string `json:"direction"`
+}
+
+// SheetMeta describes the atlas image
+type SheetMeta struct {
+	App       string     `json:"app"`
+	Version   string     `json:"version"`
+	Image     string     `json:"image"`
+	Format    string     `json:"format"`
+	Size      SheetSize  `json:"size"`
+	Scale     string     `json:"scale"`
+	FrameTags []SheetTag `json:"frameTags"`
+}
+
+// Atlas is the JSON descriptor of one atlas image, in the widespread JSON array layout
+type Atlas struct {
+	Frames []SheetFrame `json:"frames"`
+	Meta   SheetMeta    `json:"meta"`
+}
+
+// SheetPage is one packed atlas image along with its descriptor
+type SheetPage struct {
+	Image *image.RGBA
+	Atlas Atlas
+}
+
+// sheetSprite is a frame waiting to be packed
+type sheetSprite struct {
+	index  int
+	source image.Rectangle
+	page   int
+	pos    image.Point
+}
+
+// SpriteSheet packs `frames` into as few atlas images as fit within opts.MaxSize
+func SpriteSheet(frames []*image.RGBA, opts SheetOptions) ([]SheetPage, error) {
+	if len(frames) == 0 {
+		return nil, errors.New("export: no frames to pack")
+	}
+	if opts.Name == "" {
+		opts.Name = "anim8"
+	}
+	if opts.FPS < 1 {
+		opts.FPS = 1
+	}
+	if opts.MaxSize < 1 {
+		opts.MaxSize = 4096
+	}
+
+	sprites := make([]*sheetSprite, len(frames))
+	area := 0
+	widest := 0
+	for i, frame := range frames {
+		source := frame.Bounds()
+		if opts.Trim {
+			source = contentBounds(frame)
+		}
+		if source.Dx()+opts.Padding > opts.MaxSize || source.Dy()+opts.Padding > opts.MaxSize {
+			return nil, fmt.Errorf("export: frame %d does not fit into %dx%d", i, opts.MaxSize, opts.MaxSize)
+		}
+
+		sprites[i] = &sheetSprite{index: i, source: source}
+		area += (source.Dx() + opts.Padding) * (source.Dy() + opts.Padding)
+		if source.Dx()+opts.Padding > widest {
+			widest = source.Dx() + opts.Padding
+		}
+	}
+
+	// aim for a roughly square atlas
+	width := int(math.Ceil(math.Sqrt(float64(area))))
+	if width < widest {
+		width = widest
+	}
+	if width > opts.MaxSize {
+		width = opts.MaxSize
+	}
+
+	// shelf packing, tallest frames first
+	order := make([]*sheetSprite, len(sprites))
+	copy(order, sprites)
+	sort.SliceStable(order, func(i, j int) bool {
+		return order[i].source.Dy() > order[j].source.Dy()
+	})
+
+	sizes := []image.Point{{}}
+	page, x, y, shelf := 0, 0, 0, 0
+	for _, s := range order {
+		w, h := s.source.Dx()+opts.Padding, s.source.Dy()+opts.Padding
+		if x+w > width {
+			x, y, shelf = 0, y+shelf, 0
+		}
+		if y+h > opts.MaxSize {
+			page, x, y, shelf = page+1, 0, 0, 0
+			sizes = append(sizes, image.Point{})
+		}
+
+		s.page, s.pos = page, image.Pt(x, y)
+		x += w
+		if h > shelf {
+			shelf = h
+		}
+		if x > sizes[page].X {
+			sizes[page].X = x
+		}
+		if y+h > sizes[page].Y {
+			sizes[page].Y = y + h
+		}
+	}
+
+	pages := make([]SheetPage, len(sizes))
+	for i, size := range sizes {
+		file := opts.Name + ".png"
+		if len(sizes) > 1 {
+			file = fmt.Sprintf("%s-%d.png", opts.Name, i)
+		}
+
+		pages[i].Image = image.NewRGBA(image.Rect(0, 0, size.X-opts.Padding, size.Y-opts.Padding))
+		pages[i].Atlas.Meta = SheetMeta{
+			App:     "anim8",
+			Version: "1.0",
+			Image:   file,
+			Format:  "RGBA8888",
+			Size:    SheetSize{pages[i].Image.Bounds().Dx(), pages[i].Image.Bounds().Dy()},
+			Scale:   "1",
+		}
+	}
+
+	// frames are listed in animation order, so the tags are contiguous within each page.
+	// durations are rounded from the running total like the GIF delays, so that they don't drift
+	ticks, elapsed := 0, 0
+	for _, s := range sprites {
+		frame := frames[s.index]
+		dst := pages[s.page].Image
+		draw.Draw(dst, image.Rectangle{s.pos, s.pos.Add(s.source.Size())}, frame, s.source.Min, draw.Src)
+
+		hold := 1
+		if s.index < len(opts.Holds) && opts.Holds[s.index] > 0 {
+			hold = opts.Holds[s.index]
+		}
+		ticks += hold
+		duration := (2000*ticks+opts.FPS)/(2*opts.FPS) - elapsed
+		elapsed += duration
+
+		bounds := frame.Bounds()
+		atlas := &pages[s.page].Atlas
+		atlas.Frames = append(atlas.Frames, SheetFrame{
+			Filename: fmt.Sprintf("%s %d", opts.Name, s.index),
+			Frame:    SheetRect{s.pos.X, s.pos.Y, s.source.Dx(), s.source.Dy()},
+			Trimmed:  s.source != bounds,
+			SpriteSourceSize: SheetRect{
+				s.source.Min.X - bounds.Min.X,
+				s.source.Min.Y - bounds.Min.Y,
+				s.source.Dx(),
+				s.source.Dy(),
+			},
+			SourceSize: SheetSize{bounds.Dx(), bounds.Dy()},
+			Duration:   duration,
+		})
+	}
+
+	for i := range pages {
+		pages[i].Atlas.Meta.FrameTags = []SheetTag{{
+			Name:      opts.Name,
+			From:      0,
+			To:        len(pages[i].Atlas.Frames) - 1,
+			Direction: "forward",
+		}}
+	}
+
+	return pages, nil
+}
+
+// WriteSpriteSheet packs `frames` and writes the atlas images and their JSON descriptors into `dir`
+func WriteSpriteSheet(dir string, frames []*image.RGBA, opts SheetOptions) error {
+	pages, err := SpriteSheet(frames, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, page := range pages {
+		path := filepath.Join(dir, page.Atlas.Meta.Image)
+		if err := writePNG(path, page.Image); err != nil {
+			return err
+		}
+
+		data, err := json.MarshalIndent(&page.Atlas, "", "\t")
+		if err != nil {
+			return err
+		}
+
+		jsonPath := path[:len(path)-len(filepath.Ext(path))] + ".json"
+		if err := ioutil.WriteFile(jsonPath, data, 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// contentBounds returns the bounds of the non-transparent pixels of `img`, at least one pixel
+func contentBounds(img *image.RGBA) image.Rectangle {
+	bounds := img.Bounds()
+	content := image.Rectangle{}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if img.Pix[img.PixOffset(x, y)+3] != 0 {
+				content = content.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+
+	if content.Empty() {
+		return image.Rectangle{bounds.Min, bounds.Min.Add(image.Pt(1, 1))}
+	}
+	return content
+}
+
+func writePNG(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
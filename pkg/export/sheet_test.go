@@ -0,0 +1,123 @@
+package export
+
+import (
+	"image"
+	"testing"
+)
+
+func TestSpriteSheetTrim(t *testing.T) {
+	imgs := frames(3)
+	pages, err := SpriteSheet(imgs, SheetOptions{Name: "walk", Trim: true, Padding: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 1 || len(pages[0].Atlas.Frames) != 3 {
+		t.Fatalf("got %d pages, want all 3 frames on 1", len(pages))
+	}
+
+	page := pages[0]
+	if page.Atlas.Meta.Image != "walk.png" {
+		t.Errorf("image is named %q, want walk.png", page.Atlas.Meta.Image)
+	}
+	for i, f := range page.Atlas.Frames {
+		// the red square is 4x4 and moves 2 pixels right every frame
+		want := SheetRect{2 * i, 2, 4, 4}
+		if !f.Trimmed || f.SpriteSourceSize != want || f.SourceSize != (SheetSize{16, 8}) {
+			t.Errorf("frame %d is trimmed %v to %v of %v, want %v of 16x8", i, f.Trimmed, f.SpriteSourceSize, f.SourceSize, want)
+		}
+		if f.Frame.W != 4 || f.Frame.H != 4 {
+			t.Errorf("frame %d takes %dx%d pixels of the atlas, want 4x4", i, f.Frame.W, f.Frame.H)
+		}
+
+		// the packed pixels are the frame's content
+		for y := 0; y < f.Frame.H; y++ {
+			for x := 0; x < f.Frame.W; x++ {
+				got := page.Image.RGBAAt(f.Frame.X+x, f.Frame.Y+y)
+				src := imgs[i].RGBAAt(want.X+x, want.Y+y)
+				if got != src {
+					t.Fatalf("frame %d pixel %d,%d is %v, want %v", i, x, y, got, src)
+				}
+			}
+		}
+	}
+
+	// frames on the same shelf keep the padding between them
+	a, b := page.Atlas.Frames[0].Frame, page.Atlas.Frames[1].Frame
+	if image.Rect(a.X, a.Y, a.X+a.W+1, a.Y+a.H+1).Overlaps(image.Rect(b.X, b.Y, b.X+b.W, b.Y+b.H)) {
+		t.Errorf("frames %v and %v are packed without padding", a, b)
+	}
+}
+
+func TestSpriteSheetPages(t *testing.T) {
+	// two untrimmed 16x8 frames fit into 16x16
+	pages, err := SpriteSheet(frames(3), SheetOptions{Name: "walk", MaxSize: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 2 {
+		t.Fatalf("got %d pages, want 2", len(pages))
+	}
+
+	tests := []struct {
+		image  string
+		frames []string
+		size   SheetSize
+	}{
+		{"walk-0.png", []string{"walk 0", "walk 1"}, SheetSize{16, 16}},
+		{"walk-1.png", []string{"walk 2"}, SheetSize{16, 8}},
+	}
+	for i, test := range tests {
+		atlas := pages[i].Atlas
+		if atlas.Meta.Image != test.image || atlas.Meta.Size != test.size {
+			t.Errorf("page %d is %q of %v, want %q of %v", i, atlas.Meta.Image, atlas.Meta.Size, test.image, test.size)
+		}
+		if len(atlas.Frames) != len(test.frames) {
+			t.Errorf("page %d has %d frames, want %d", i, len(atlas.Frames), len(test.frames))
+			continue
+		}
+		for j, f := range atlas.Frames {
+			if f.Filename != test.frames[j] || f.Trimmed {
+				t.Errorf("page %d frame %d is %q trimmed %v, want untrimmed %q", i, j, f.Filename, f.Trimmed, test.frames[j])
+			}
+		}
+		if tag := atlas.Meta.FrameTags[0]; tag.From != 0 || tag.To != len(test.frames)-1 {
+			t.Errorf("page %d tags frames %d to %d", i, tag.From, tag.To)
+		}
+	}
+
+	if _, err := SpriteSheet(frames(1), SheetOptions{MaxSize: 8}); err == nil {
+		t.Error("packed a frame larger than the atlas")
+	}
+}
+
+func TestSpriteSheetDurations(t *testing.T) {
+	tests := []struct {
+		fps   int
+		holds []int
+		want  []int
+	}{
+		// 66.67ms per frame, rounded in turns so that 3 frames last 200ms
+		{15, nil, []int{67, 66, 67}},
+		{15, []int{1, 2, 1}, []int{67, 133, 67}},
+		{24, []int{2}, []int{83, 42, 42}},
+		{10, []int{3, 0, -1}, []int{300, 100, 100}},
+	}
+
+	for _, test := range tests {
+		pages, err := SpriteSheet(frames(3), SheetOptions{FPS: test.fps, Holds: test.holds})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var durations []int
+		for _, f := range pages[0].Atlas.Frames {
+			durations = append(durations, f.Duration)
+		}
+		for i, d := range durations {
+			if d != test.want[i] {
+				t.Errorf("%d FPS with holds %v: durations are %v, want %v", test.fps, test.holds, durations, test.want)
+				break
+			}
+		}
+	}
+}
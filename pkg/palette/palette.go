@@ -0,0 +1,204 @@
+package palette
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Palette is a named list of opaque colors, shared between artists as GIMP (.gpl), plain hex
+// (.hex) or JASC (.pal) palette files
+type Palette struct {
+	Name   string
+	Colors []color.RGBA
+}
+
+// Load reads the palette file at `path`, the format is told by the file extension
+func Load(path string) (*Palette, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var p *Palette
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gpl":
+		p, err = DecodeGPL(file)
+	case ".hex":
+		p, err = DecodeHex(file)
+	case ".pal":
+		p, err = DecodePAL(file)
+	default:
+		return nil, fmt.Errorf("palette: unknown format %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("palette: %s: %v", path, err)
+	}
+
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return p, nil
+}
+
+// Save writes `p` to the file at `path`, the format is told by the file extension
+func Save(path string, p *Palette) error {
+	var encode func(io.Writer, *Palette) error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gpl":
+		encode = EncodeGPL
+	case ".hex":
+		encode = EncodeHex
+	case ".pal":
+		encode = EncodePAL
+	default:
+		return fmt.Errorf("palette: unknown format %q", filepath.Ext(path))
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := encode(file, p); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// DecodeGPL reads a GIMP palette
+func DecodeGPL(r io.Reader) (*Palette, error) {
+	lines := bufio.NewScanner(r)
+	if !lines.Scan() || strings.TrimSpace(lines.Text()) != "GIMP Palette" {
+		return nil, errors.New("missing GIMP Palette header")
+	}
+
+	p := &Palette{}
+	for n := 2; lines.Scan(); n++ {
+		line := strings.TrimSpace(lines.Text())
+
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "Name:"):
+			p.Name = strings.TrimSpace(strings.TrimPrefix(line, "Name:"))
+			continue
+		case strings.HasPrefix(line, "Columns:"):
+			continue
+		}
+
+		// every color is "R G B", optionally followed by its name
+		var c color.RGBA
+		if _, err := fmt.Sscan(line, &c.R, &c.G, &c.B); err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		c.A = 255
+		p.Colors = append(p.Colors, c)
+	}
+
+	return p, lines.Err()
+}
+
+// EncodeGPL writes `p` as a GIMP palette
+func EncodeGPL(w io.Writer, p *Palette) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "GIMP Palette\nName: %s\nColumns: 8\n#\n", p.Name)
+	for _, c := range p.Colors {
+		fmt.Fprintf(bw, "%3d %3d %3d\t#%02x%02x%02x\n", c.R, c.G, c.B, c.R, c.G, c.B)
+	}
+	return bw.Flush()
+}
+
+// DecodeHex reads a palette with a color per line written as rrggbb, with or without #
+func DecodeHex(r io.Reader) (*Palette, error) {
+	lines := bufio.NewScanner(r)
+
+	p := &Palette{}
+	for n := 1; lines.Scan(); n++ {
+		line := strings.TrimPrefix(strings.TrimSpace(lines.Text()), "#")
+		if line == "" {
+			continue
+		}
+
+		// Sscanf would stop at the first character that isn't a hex digit without complaining
+		v, err := strconv.ParseUint(line, 16, 32)
+		if len(line) != 6 || err != nil {
+			return nil, fmt.Errorf("line %d: expected rrggbb", n)
+		}
+		p.Colors = append(p.Colors, color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255})
+	}
+
+	return p, lines.Err()
+}
+
+// EncodeHex writes `p` with a color per line written as rrggbb
+func EncodeHex(w io.Writer, p *Palette) error {
+	bw := bufio.NewWriter(w)
+	for _, c := range p.Colors {
+		fmt.Fprintf(bw, "%02x%02x%02x\n", c.R, c.G, c.B)
+	}
+	return bw.Flush()
+}
+
+// DecodePAL reads a JASC palette
+func DecodePAL(r io.Reader) (*Palette, error) {
+	lines := bufio.NewScanner(r)
+
+	header := make([]string, 3)
+	for i := range header {
+		if !lines.Scan() {
+			return nil, errors.New("missing JASC-PAL header")
+		}
+		header[i] = strings.TrimSpace(lines.Text())
+	}
+	if header[0] != "JASC-PAL" {
+		return nil, errors.New("missing JASC-PAL header")
+	}
+
+	var count int
+	if _, err := fmt.Sscan(header[2], &count); err != nil {
+		return nil, fmt.Errorf("line 3: %v", err)
+	}
+
+	p := &Palette{}
+	for n := 4; len(p.Colors) < count && lines.Scan(); n++ {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" {
+			continue
+		}
+
+		var c color.RGBA
+		if _, err := fmt.Sscan(line, &c.R, &c.G, &c.B); err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		c.A = 255
+		p.Colors = append(p.Colors, c)
+	}
+	if err := lines.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(p.Colors) < count {
+		return nil, fmt.Errorf("expected %d colors, found %d", count, len(p.Colors))
+	}
+	return p, nil
+}
+
+// EncodePAL writes `p` as a JASC palette
+func EncodePAL(w io.Writer, p *Palette) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "JASC-PAL\r\n0100\r\n%d\r\n", len(p.Colors))
+	for _, c := range p.Colors {
+		fmt.Fprintf(bw, "%d %d %d\r\n", c.R, c.G, c.B)
+	}
+	return bw.Flush()
+}
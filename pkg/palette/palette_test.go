@@ -0,0 +1,76 @@
+package palette
+
+import (
+	"bytes"
+	"image/color"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var colors = []color.RGBA{
+	{0, 0, 0, 255},
+	{255, 128, 7, 255},
+	{18, 52, 86, 255},
+}
+
+func TestRoundTrip(t *testing.T) {
+	formats := []struct {
+		name   string
+		encode func(io.Writer, *Palette) error
+		decode func(io.Reader) (*Palette, error)
+	}{
+		{"gpl", EncodeGPL, DecodeGPL},
+		{"hex", EncodeHex, DecodeHex},
+		{"pal", EncodePAL, DecodePAL},
+	}
+
+	for _, f := range formats {
+		var buf bytes.Buffer
+		if err := f.encode(&buf, &Palette{Name: "Sunset", Colors: colors}); err != nil {
+			t.Fatal(err)
+		}
+		p, err := f.decode(&buf)
+		if err != nil {
+			t.Fatalf("%s: %v", f.name, err)
+		}
+		if !reflect.DeepEqual(p.Colors, colors) {
+			t.Errorf("%s: colors are %v, want %v", f.name, p.Colors, colors)
+		}
+	}
+}
+
+func TestDecodeGPL(t *testing.T) {
+	p, err := DecodeGPL(strings.NewReader("GIMP Palette\nName: Sunset\nColumns: 4\n# comment\n  0   0   0\tBlack\n255 128   7 Orange\n\n18 52 86\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "Sunset" || !reflect.DeepEqual(p.Colors, colors) {
+		t.Errorf("got %q %v", p.Name, p.Colors)
+	}
+
+	if _, err := DecodeGPL(strings.NewReader("JASC-PAL\n")); err == nil {
+		t.Error("decoded a palette without GIMP header")
+	}
+}
+
+func TestDecodeHex(t *testing.T) {
+	p, err := DecodeHex(strings.NewReader("000000\n#ff8007\r\n\n123456\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p.Colors, colors) {
+		t.Errorf("got %v", p.Colors)
+	}
+
+	if _, err := DecodeHex(strings.NewReader("00000g\n")); err == nil {
+		t.Error("decoded an invalid color")
+	}
+}
+
+func TestDecodePAL(t *testing.T) {
+	if _, err := DecodePAL(strings.NewReader("JASC-PAL\r\n0100\r\n4\r\n0 0 0\r\n")); err == nil {
+		t.Error("decoded a palette missing colors")
+	}
+}
@@ -0,0 +1,197 @@
+package project
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/supermuesli/anim8/pkg/scene"
+)
+
+// the JSON layout of the frames in the manifest, kept apart from the scene types so that the
+// scene can change without breaking older files
+
+type backgroundJSON struct {
+	// written by scene.FormatColor
+	Color string `json:"color"`
+	Image string `json:"image,omitempty"`
+}
+
+type frameJSON struct {
+	Layers []layerJSON `json:"layers"`
+
+	// missing for frames shown for a single tick
+	Hold int `json:"hold,omitempty"`
+}
+
+type layerJSON struct {
+	Name   string `json:"name"`
+	Hidden bool   `json:"hidden,omitempty"`
+	Locked bool   `json:"locked,omitempty"`
+
+	// missing in files written before layers had an opacity, which means opaque
+	Opacity *float64 `json:"opacity,omitempty"`
+
+	Image   string       `json:"image,omitempty"`
+	Offset  [2]int       `json:"offset"`
+	Strokes []strokeJSON `json:"strokes"`
+}
+
+type strokeJSON struct {
+	Erase bool `json:"erase,omitempty"`
+
+	// see brush.Presets and scene.Tips
+	Brush string `json:"brush,omitempty"`
+	Tip   string `json:"tip,omitempty"`
+
+	// missing in files written before strokes had an opacity, which means opaque
+	Opacity *float64 `json:"opacity,omitempty"`
+
+	Seed int64 `json:"seed,omitempty"`
+
+	// written by scene.FormatColor, the color of every point unless Colors has one per point.
+	// Strokes of files written before strokes had a color are white.
+	Color  string   `json:"color,omitempty"`
+	Colors []string `json:"colors,omitempty"`
+
+	// every point is [x, y, width, alpha]
+	Points [][4]float64 `json:"points,omitempty"`
+
+	// version 2 stored the imprints of the brush tip instead of the path, every stamp is
+	// [x, y, scale], or [x, y, scale, angle, alpha]
+	Stamps [][]float64 `json:"stamps,omitempty"`
+}
+
+// defaultTipSize is how many pixels across the default tip is, which the stamps of version 2
+// are scaled relative to
+const defaultTipSize = 100
+
+func encodeLayer(l *scene.Layer) layerJSON {
+	opacity := l.Opacity
+	lj := layerJSON{
+		Name:    l.Name,
+		Hidden:  l.Hidden,
+		Locked:  l.Locked,
+		Opacity: &opacity,
+		Offset:  [2]int{l.Offset.X, l.Offset.Y},
+		Strokes: make([]strokeJSON, len(l.Strokes)),
+	}
+
+	for i, s := range l.Strokes {
+		lj.Strokes[i] = encodeStroke(s)
+	}
+
+	return lj
+}
+
+func encodeStroke(s *scene.Stroke) strokeJSON {
+	opacity := s.Opacity
+	sj := strokeJSON{
+		Erase:   s.Erase,
+		Brush:   s.Brush,
+		Tip:     s.Tip,
+		Opacity: &opacity,
+		Seed:    s.Seed,
+		Points:  make([][4]float64, len(s.Points)),
+	}
+
+	for i, p := range s.Points {
+		sj.Points[i] = [4]float64{p.X, p.Y, p.Width, p.Alpha}
+	}
+
+	// only write a color per point if they differ
+	if !s.Erase && len(s.Points) > 0 {
+		sj.Color = scene.FormatColor(s.Points[0].Color)
+		for _, p := range s.Points {
+			if p.Color != s.Points[0].Color {
+				sj.Colors = make([]string, len(s.Points))
+				for i, p := range s.Points {
+					sj.Colors[i] = scene.FormatColor(p.Color)
+				}
+				break
+			}
+		}
+	}
+
+	return sj
+}
+
+// decodeLayer turns `lj` back into a layer, the tips are needed for the stamps of version 2
+func decodeLayer(lj layerJSON, tips map[string]*image.RGBA) (*scene.Layer, error) {
+	l := &scene.Layer{
+		Name:    lj.Name,
+		Hidden:  lj.Hidden,
+		Locked:  lj.Locked,
+		Opacity: 1,
+		Offset:  image.Pt(lj.Offset[0], lj.Offset[1]),
+		Strokes: make([]*scene.Stroke, len(lj.Strokes)),
+	}
+
+	for i, sj := range lj.Strokes {
+		s, err := decodeStroke(sj, tips)
+		if err != nil {
+			return nil, err
+		}
+		l.Strokes[i] = s
+	}
+
+	if lj.Opacity != nil {
+		l.Opacity = *lj.Opacity
+	}
+
+	return l, nil
+}
+
+func decodeStroke(sj strokeJSON, tips map[string]*image.RGBA) (*scene.Stroke, error) {
+	s := &scene.Stroke{Erase: sj.Erase, Brush: sj.Brush, Tip: sj.Tip, Opacity: 1, Seed: sj.Seed}
+	if sj.Opacity != nil {
+		s.Opacity = *sj.Opacity
+	}
+
+	c := color.RGBA{}
+	if !sj.Erase {
+		c = color.RGBA{255, 255, 255, 255}
+	}
+	if sj.Color != "" {
+		var err error
+		if c, err = scene.ParseColor(sj.Color); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, p := range sj.Points {
+		s.Points = append(s.Points, scene.Point{X: p[0], Y: p[1], Width: p[2], Alpha: p[3], Color: c})
+	}
+
+	// every stamp becomes a point as wide as the tip was stretched
+	tipSize := float64(defaultTipSize)
+	if tip, ok := tips[sj.Tip]; ok {
+		tipSize = math.Max(float64(tip.Rect.Dx()), float64(tip.Rect.Dy()))
+	}
+	for _, st := range sj.Stamps {
+		if len(st) != 3 && len(st) != 5 {
+			return nil, fmt.Errorf("project: stamp with %d values", len(st))
+		}
+		p := scene.Point{X: st[0], Y: st[1], Width: st[2] * tipSize, Alpha: 1, Color: c}
+		if len(st) == 5 {
+			p.Alpha = st[4]
+		}
+		s.Points = append(s.Points, p)
+	}
+
+	if sj.Colors != nil {
+		if len(sj.Colors) != len(s.Points) {
+			return nil, fmt.Errorf("project: %d colors for %d points", len(sj.Colors), len(s.Points))
+		}
+		for i, hex := range sj.Colors {
+			c, err := scene.ParseColor(hex)
+			if err != nil {
+				return nil, err
+			}
+			s.Points[i].Color = c
+		}
+	}
+
+	return s, nil
+}
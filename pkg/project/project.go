@@ -0,0 +1,407 @@
+package project
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/supermuesli/anim8/pkg/scene"
+)
+
+// Version is the newest project format version this package can read and write
+//
+//	1: every frame is a flattened PNG
+//	2: frames are layers of strokes, on top of an optional PNG per layer
+//	3: strokes are paths that brush presets render, rather than imprints of the brush tip
+const Version = 3
+
+// Extension is the file extension of anim8 project files
+const Extension = ".anim8"
+
+const manifestName = "project.json"
+
+const backgroundName = "background.png"
+
+// Brush holds the brush settings that were active when the project was saved
+type Brush struct {
+	Size    float64 `json:"size"`
+	Erasing bool    `json:"erasing"`
+
+	// Color is written by scene.FormatColor
+	Color string `json:"color,omitempty"`
+
+	// Preset is the name of the brush preset, see brush.Presets
+	Preset string `json:"preset,omitempty"`
+
+	// Tip is the name of the tip replacing the one of the preset, see scene.Tips
+	Tip string `json:"tip,omitempty"`
+
+	// Smoothing is the strength of the stabilizer, which smooths strokes while Stabilizing
+	Smoothing   int  `json:"smoothing,omitempty"`
+	Stabilizing bool `json:"stabilizing,omitempty"`
+
+	// Pressure is simulated from how fast strokes are painted, see brush.Brush
+	Pressure bool `json:"pressure,omitempty"`
+}
+
+// Onion holds how the frames around the current one were shown when the project was saved
+type Onion struct {
+	Enabled bool `json:"enabled"`
+
+	// Before and After are how many previous and next frames are shown
+	Before int `json:"before"`
+	After  int `json:"after"`
+
+	// Opacity of the frames next to the current one, which every frame further away is shown
+	// with Falloff times as much of
+	Opacity float64 `json:"opacity"`
+	Falloff float64 `json:"falloff"`
+
+	// TintBefore and TintAfter are the colors previous and next frames are shown in, written by
+	// scene.FormatColor. They are missing in files written before the tints could be changed.
+	TintBefore string `json:"tintBefore,omitempty"`
+	TintAfter  string `json:"tintAfter,omitempty"`
+}
+
+// Project is everything needed to reopen a scene where it was left off
+type Project struct {
+	Scene *scene.Scene
+	Brush Brush
+
+	// Onion is nil for projects saved before onion skins could be configured
+	Onion *Onion
+}
+
+// manifest is the JSON document stored next to the images
+type manifest struct {
+	Version     int             `json:"version"`
+	Name        string          `json:"name"`
+	Width       int             `json:"width"`
+	Height      int             `json:"height"`
+	PlaybackFPS int             `json:"playbackFPS"`
+	Current     int             `json:"current"`
+	Layer       int             `json:"layer"`
+	Brush       Brush           `json:"brush"`
+	Onion       *Onion          `json:"onion,omitempty"`
+	Background  *backgroundJSON `json:"background,omitempty"`
+
+	// the images of scene.Tips by name
+	Tips map[string]string `json:"tips,omitempty"`
+
+	Frames json.RawMessage `json:"frames"`
+}
+
+// FileName returns `name` with the project extension appended, unless it already has it
+func FileName(name string) string {
+	if strings.HasSuffix(name, Extension) {
+		return name
+	}
+	return name + Extension
+}
+
+// Save writes the project to the file at `path`
+func Save(path string, p *Project) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := Encode(file, p); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// Load reads the project stored in the file at `path`
+func Load(path string) (*Project, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	return Decode(file, info.Size())
+}
+
+// Encode writes the project as a zip archive containing a JSON manifest and the layer images
+func Encode(w io.Writer, p *Project) error {
+	s := p.Scene
+	archive := zip.NewWriter(w)
+
+	frames := make([]frameJSON, len(s.Frames))
+	for i, f := range s.Frames {
+		frames[i].Layers = make([]layerJSON, len(f.Layers))
+		if f.Hold > 1 {
+			frames[i].Hold = f.Hold
+		}
+		for j, l := range f.Layers {
+			frames[i].Layers[j] = encodeLayer(l)
+
+			if l.Image == nil {
+				continue
+			}
+
+			name := fmt.Sprintf("layers/%06d-%02d.png", i, j)
+			frames[i].Layers[j].Image = name
+
+			// the PNGs are already deflated, so just store them
+			fw, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+			if err != nil {
+				return err
+			}
+			if err := png.Encode(fw, l.Image); err != nil {
+				return err
+			}
+		}
+	}
+
+	raw, err := json.Marshal(frames)
+	if err != nil {
+		return err
+	}
+
+	background := &backgroundJSON{Color: scene.FormatColor(s.Background.Color)}
+	if s.Background.Image != nil {
+		background.Image = backgroundName
+
+		fw, err := archive.CreateHeader(&zip.FileHeader{Name: backgroundName, Method: zip.Store})
+		if err != nil {
+			return err
+		}
+		if err := png.Encode(fw, s.Background.Image); err != nil {
+			return err
+		}
+	}
+
+	tips := make(map[string]string, len(s.Tips))
+	for name, tip := range s.Tips {
+		tips[name] = "tips/" + name + ".png"
+
+		fw, err := archive.CreateHeader(&zip.FileHeader{Name: tips[name], Method: zip.Store})
+		if err != nil {
+			return err
+		}
+		if err := png.Encode(fw, tip); err != nil {
+			return err
+		}
+	}
+
+	m := manifest{
+		Version:     Version,
+		Name:        s.Name,
+		Width:       s.Width,
+		Height:      s.Height,
+		PlaybackFPS: s.FPS,
+		Current:     s.Current,
+		Layer:       s.Layer,
+		Brush:       p.Brush,
+		Onion:       p.Onion,
+		Background:  background,
+		Tips:        tips,
+		Frames:      raw,
+	}
+
+	mw, err := archive.Create(manifestName)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(mw)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(&m); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+// Decode reads a project that was written by Encode, in any format version up to Version
+func Decode(r io.ReaderAt, size int64) (*Project, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+
+	mf, ok := files[manifestName]
+	if !ok {
+		return nil, fmt.Errorf("project: missing %s", manifestName)
+	}
+
+	var m manifest
+	if err := readJSON(mf, &m); err != nil {
+		return nil, err
+	}
+	if m.Width < 1 || m.Height < 1 {
+		return nil, fmt.Errorf("project: invalid size %dx%d", m.Width, m.Height)
+	}
+
+	s := &scene.Scene{
+		Name:       m.Name,
+		Width:      m.Width,
+		Height:     m.Height,
+		FPS:        m.PlaybackFPS,
+		Background: scene.DefaultBackground,
+		Current:    m.Current,
+		Layer:      m.Layer,
+		Tips:       make(map[string]*image.RGBA, len(m.Tips)),
+	}
+
+	for name, file := range m.Tips {
+		if s.Tips[name], err = readImage(files, file); err != nil {
+			return nil, err
+		}
+	}
+
+	// files written before scenes had a background are on the default one
+	if m.Background != nil {
+		if s.Background.Color, err = scene.ParseColor(m.Background.Color); err != nil {
+			return nil, fmt.Errorf("project: background: %v", err)
+		}
+		if m.Background.Image != "" {
+			if s.Background.Image, err = readImage(files, m.Background.Image); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	switch m.Version {
+	case 1:
+		err = decodeV1(s, m.Frames, files)
+	case 2, 3:
+		err = decodeV2(s, m.Frames, files)
+	default:
+		err = fmt.Errorf("project: unsupported format version %d", m.Version)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(s.Frames) == 0 {
+		s.Frames = []*scene.Frame{scene.NewFrame()}
+	}
+	if s.Current < 0 || s.Current >= len(s.Frames) {
+		s.Current = 0
+	}
+	if s.FPS < 1 {
+		s.FPS = scene.DefaultFPS
+	}
+
+	return &Project{Scene: s, Brush: m.Brush, Onion: m.Onion}, nil
+}
+
+// decodeV1 turns the flattened frames of version 1 into frames with a single image layer
+func decodeV1(s *scene.Scene, raw json.RawMessage, files map[string]*zip.File) error {
+	var names []string
+	if err := json.Unmarshal(raw, &names); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		img, err := readImage(files, name)
+		if err != nil {
+			return err
+		}
+
+		f := scene.NewFrame()
+		f.Layers[0].Image = img
+		s.Frames = append(s.Frames, f)
+	}
+
+	return nil
+}
+
+// decodeV2 reads layers of strokes, version 3 only changed how strokes are stored, see decodeStroke
+func decodeV2(s *scene.Scene, raw json.RawMessage, files map[string]*zip.File) error {
+	var frames []frameJSON
+	if err := json.Unmarshal(raw, &frames); err != nil {
+		return err
+	}
+
+	for _, fj := range frames {
+		f := &scene.Frame{Hold: fj.Hold}
+		for _, lj := range fj.Layers {
+			l, err := decodeLayer(lj, s.Tips)
+			if err != nil {
+				return err
+			}
+			if lj.Image != "" {
+				img, err := readImage(files, lj.Image)
+				if err != nil {
+					return err
+				}
+				l.Image = img
+			}
+			f.Layers = append(f.Layers, l)
+		}
+
+		if len(f.Layers) == 0 {
+			f = scene.NewFrame()
+			f.Hold = fj.Hold
+		}
+		s.Frames = append(s.Frames, f)
+	}
+
+	return nil
+}
+
+func readJSON(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	return json.NewDecoder(rc).Decode(v)
+}
+
+func readImage(files map[string]*zip.File, name string) (*image.RGBA, error) {
+	f, ok := files[name]
+	if !ok {
+		return nil, fmt.Errorf("project: missing image %s", name)
+	}
+
+	img, err := readPNG(f)
+	if err != nil {
+		return nil, fmt.Errorf("project: image %s: %v", name, err)
+	}
+	return img, nil
+}
+
+func readPNG(f *zip.File) (*image.RGBA, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	img, err := png.Decode(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == image.ZP {
+		return rgba, nil
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return rgba, nil
+}
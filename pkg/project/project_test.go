@@ -0,0 +1,190 @@
+package project
+
+import (
+	"archive/zip"
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"reflect"
+	"testing"
+
+	"github.com/supermuesli/anim8/pkg/scene"
+)
+
+// archive zips `files` up the way a project file of an older version would have been written
+func archive(t *testing.T, files map[string][]byte) *bytes.Reader {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, data := range files {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	s := scene.New("walk", 32, 16)
+	s.FPS = 12
+	s.Background = scene.Background{Color: color.RGBA{10, 20, 30, 255}}
+
+	stroke := &scene.Stroke{Brush: "Ink", Opacity: 0.5, Seed: 7, Points: []scene.Point{
+		{X: 1, Y: 2, Width: 3, Alpha: 1, Color: color.RGBA{255, 0, 0, 255}},
+		{X: 4.5, Y: 5.25, Width: 6, Alpha: 0.5, Color: color.RGBA{0, 0, 255, 255}},
+	}}
+	eraser := &scene.Stroke{Erase: true, Opacity: 1, Seed: 8, Points: []scene.Point{{X: 1, Y: 1, Width: 2, Alpha: 1}}}
+	s.Frames[0].Layers[0].Strokes = []*scene.Stroke{stroke, eraser}
+	s.Frames[0].Layers[0].Opacity = 0.25
+	s.Frames[0].Hold = 3
+
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.SetRGBA(1, 1, color.RGBA{1, 2, 3, 255})
+	second := scene.NewFrame()
+	second.Layers[0].Image = img
+	second.Layers[0].Offset = image.Pt(4, 5)
+	second.Layers[0].Hidden = true
+	s.Frames = append(s.Frames, second)
+	s.Current = 1
+
+	p := &Project{
+		Scene: s,
+		Brush: Brush{Size: 4, Color: "#ff0000", Preset: "Ink", Smoothing: 3},
+		Onion: &Onion{Enabled: true, Before: 2, After: 1, Opacity: 0.4, Falloff: 0.5},
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := got.Scene
+	if g.Name != s.Name || g.Width != s.Width || g.Height != s.Height || g.FPS != s.FPS || g.Current != s.Current {
+		t.Errorf("scene is %s %dx%d at %d FPS on frame %d", g.Name, g.Width, g.Height, g.FPS, g.Current)
+	}
+	if g.Background.Color != s.Background.Color {
+		t.Errorf("background is %v, want %v", g.Background.Color, s.Background.Color)
+	}
+	if !reflect.DeepEqual(got.Brush, p.Brush) || !reflect.DeepEqual(got.Onion, p.Onion) {
+		t.Errorf("settings are %+v %+v, want %+v %+v", got.Brush, got.Onion, p.Brush, p.Onion)
+	}
+	if len(g.Frames) != 2 {
+		t.Fatalf("got %d frames, want 2", len(g.Frames))
+	}
+	if g.Frames[0].Ticks() != 3 || g.Frames[1].Ticks() != 1 {
+		t.Errorf("holds are %d and %d, want 3 and 1", g.Frames[0].Ticks(), g.Frames[1].Ticks())
+	}
+
+	l := g.Frames[0].Layers[0]
+	if l.Opacity != 0.25 || len(l.Strokes) != 2 {
+		t.Fatalf("layer has opacity %v and %d strokes", l.Opacity, len(l.Strokes))
+	}
+	if !reflect.DeepEqual(l.Strokes[0], stroke) || !reflect.DeepEqual(l.Strokes[1], eraser) {
+		t.Errorf("strokes are %+v %+v, want %+v %+v", l.Strokes[0], l.Strokes[1], stroke, eraser)
+	}
+
+	l = g.Frames[1].Layers[0]
+	if !l.Hidden || l.Offset != second.Layers[0].Offset || l.Image == nil || !bytes.Equal(l.Image.Pix, img.Pix) {
+		t.Errorf("image layer is %+v", l)
+	}
+}
+
+func TestDecodeV1(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	img.SetRGBA(2, 2, color.RGBA{255, 255, 255, 255})
+
+	r := archive(t, map[string][]byte{
+		"project.json":      []byte(`{"version": 1, "name": "old", "width": 4, "height": 4, "playbackFPS": 8, "frames": ["frames/000000.png", "frames/000001.png"]}`),
+		"frames/000000.png": encodePNG(t, img),
+		"frames/000001.png": encodePNG(t, img),
+	})
+	p, err := Decode(r, r.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := p.Scene
+	if s.FPS != 8 || len(s.Frames) != 2 || p.Onion != nil {
+		t.Fatalf("scene at %d FPS with %d frames and onion %v", s.FPS, len(s.Frames), p.Onion)
+	}
+	if l := s.Frames[1].Layers[0]; l.Image == nil || !bytes.Equal(l.Image.Pix, img.Pix) || l.Opacity != 1 {
+		t.Errorf("frame image layer is %+v", l)
+	}
+}
+
+func TestDecodeV2Stamps(t *testing.T) {
+	r := archive(t, map[string][]byte{
+		"project.json": []byte(`{"version": 2, "width": 8, "height": 8, "frames": [
+			{"layers": [{"name": "Ink", "offset": [0, 0], "strokes": [
+				{"stamps": [[1, 2, 0.1], [3, 4, 0.2, 1.5, 0.5]]},
+				{"erase": true, "stamps": [[5, 6, 0.05]]}
+			]}], "hold": 2}
+		]}`),
+	})
+	p, err := Decode(r, r.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := p.Scene.Frames[0]
+	if f.Ticks() != 2 {
+		t.Errorf("hold is %d, want 2", f.Ticks())
+	}
+
+	// strokes without a color are white, stamps are scaled by the size of the default tip
+	white := color.RGBA{255, 255, 255, 255}
+	want := []scene.Point{
+		{X: 1, Y: 2, Width: 10, Alpha: 1, Color: white},
+		{X: 3, Y: 4, Width: 20, Alpha: 0.5, Color: white},
+	}
+	strokes := f.Layers[0].Strokes
+	if len(strokes) != 2 {
+		t.Fatalf("got %d strokes, want 2", len(strokes))
+	}
+	if !reflect.DeepEqual(strokes[0].Points, want) || strokes[0].Opacity != 1 {
+		t.Errorf("stroke is %+v, want points %+v", strokes[0], want)
+	}
+	if e := strokes[1]; !e.Erase || len(e.Points) != 1 || e.Points[0].Width != 5 || e.Points[0].Color != (color.RGBA{}) {
+		t.Errorf("erase stroke is %+v", e)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	r := archive(t, map[string][]byte{
+		"project.json": []byte(`{"version": 99, "width": 8, "height": 8, "frames": []}`),
+	})
+	if _, err := Decode(r, r.Size()); err == nil {
+		t.Error("decoded an unsupported version")
+	}
+
+	r = archive(t, map[string][]byte{
+		"project.json": []byte(`{"version": 3, "width": 0, "height": 8, "frames": []}`),
+	})
+	if _, err := Decode(r, r.Size()); err == nil {
+		t.Error("decoded a project without width")
+	}
+
+	r = archive(t, map[string][]byte{"frames.json": []byte(`[]`)})
+	if _, err := Decode(r, r.Size()); err == nil {
+		t.Error("decoded an archive without manifest")
+	}
+}
@@ -0,0 +1,336 @@
+package raster
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	xdraw "golang.org/x/image/draw"
+
+	"github.com/supermuesli/anim8/pkg/brush"
+	"github.com/supermuesli/anim8/pkg/scene"
+)
+
+// Rasterizer renders frames of a scene on the CPU, giving the same pixels on every machine
+type Rasterizer struct {
+	// premultiplied brush tips by name with their origin in the top left corner, "" is the
+	// default tip
+	tips map[string]*image.RGBA
+}
+
+// New creates a Rasterizer that stamps strokes with the brush tip `tip`, unless they name
+// another tip, see SetTip
+func New(tip image.Image) *Rasterizer {
+	r := &Rasterizer{tips: make(map[string]*image.RGBA)}
+	r.SetTip("", tip)
+	return r
+}
+
+// SetTip makes `tip` the brush tip that strokes naming `name` are stamped with, see scene.Tips
+func (r *Rasterizer) SetTip(name string, tip image.Image) {
+	bounds := tip.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), tip, bounds.Min, draw.Src)
+
+	r.tips[name] = rgba
+}
+
+// SetTips makes the brush tips of `s` known, see SetTip
+func (r *Rasterizer) SetTips(s *scene.Scene) {
+	for name, tip := range s.Tips {
+		r.SetTip(name, tip)
+	}
+}
+
+// tip returns the brush tip named `name`, the default tip if there is none
+func (r *Rasterizer) tip(name string) *image.RGBA {
+	if tip, ok := r.tips[name]; ok {
+		return tip
+	}
+	return r.tips[""]
+}
+
+// Scene renders every frame of `s`
+func (r *Rasterizer) Scene(s *scene.Scene) []*image.RGBA {
+	return r.Frames(s, s.Frames)
+}
+
+// Frames renders `frames` of `s`
+func (r *Rasterizer) Frames(s *scene.Scene, frames []*scene.Frame) []*image.RGBA {
+	imgs := make([]*image.RGBA, len(frames))
+	for i, f := range frames {
+		imgs[i] = r.Frame(s, f)
+	}
+	return imgs
+}
+
+// Frame renders `f` on the background of `s`, at the size of `s`
+func (r *Rasterizer) Frame(s *scene.Scene, f *scene.Frame) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, s.Width, s.Height))
+	Background(img, s.Background)
+	r.composite(img, r.Flatten(f, s.Width, s.Height), 1)
+	return img
+}
+
+// Background fills `dst` with `bg`
+func Background(dst *image.RGBA, bg scene.Background) {
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(bg.Color), image.ZP, draw.Src)
+
+	if bg.Image != nil {
+		xdraw.BiLinear.Scale(dst, dst.Bounds(), bg.Image, bg.Image.Bounds(), draw.Over, nil)
+	}
+}
+
+// Flatten composites the visible layers of `f` into a new transparent `width` x `height` image
+func (r *Rasterizer) Flatten(f *scene.Frame, width int, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for _, l := range f.Layers {
+		if l.Hidden {
+			continue
+		}
+		r.composite(img, r.Layer(l, width, height), l.Opacity)
+	}
+
+	return img
+}
+
+// Layer renders `l` on its own into a new transparent `width` x `height` image, regardless of
+// its opacity and visibility
+func (r *Rasterizer) Layer(l *scene.Layer, width int, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	if l.Image != nil {
+		bounds := l.Image.Bounds()
+		draw.Draw(img, bounds.Sub(bounds.Min).Add(l.Offset), l.Image, bounds.Min, draw.Over)
+	}
+
+	for _, s := range l.Strokes {
+		r.Stroke(img, s)
+	}
+
+	return img
+}
+
+// Layers renders the layer at index `i` of every frame with its opacity, for exporting layers
+// separately. Frames without that layer stay transparent.
+func (r *Rasterizer) Layers(frames []*scene.Frame, i int, width int, height int) []*image.RGBA {
+	imgs := make([]*image.RGBA, len(frames))
+	for j, f := range frames {
+		imgs[j] = image.NewRGBA(image.Rect(0, 0, width, height))
+		if i < len(f.Layers) {
+			r.composite(imgs[j], r.Layer(f.Layers[i], width, height), f.Layers[i].Opacity)
+		}
+	}
+	return imgs
+}
+
+// composite draws the layer image `src` over `dst` with the given opacity
+func (r *Rasterizer) composite(dst *image.RGBA, src *image.RGBA, opacity float64) {
+	if opacity >= 1 {
+		draw.Draw(dst, dst.Bounds(), src, image.ZP, draw.Over)
+		return
+	}
+	if opacity <= 0 {
+		return
+	}
+
+	mask := image.NewUniform(color.Alpha{round(opacity * 255)})
+	draw.DrawMask(dst, dst.Bounds(), src, image.ZP, mask, image.ZP, draw.Over)
+}
+
+// Stroke renders `s` from its path onto `dst`, erasing strokes take away coverage instead
+func (r *Rasterizer) Stroke(dst *image.RGBA, s *scene.Stroke) {
+	tip := r.tip(s.Tip)
+	dabs := brush.Dabs(brush.Of(s), s.Points, s.Seed)
+
+	if s.Opacity >= 1 {
+		for _, d := range dabs {
+			if s.Erase {
+				erase(dst, tip, d)
+			} else {
+				stamp(dst, tip, d, d.Color)
+			}
+		}
+		return
+	}
+	if s.Opacity <= 0 {
+		return
+	}
+
+	// the dabs build up on their own first, so that the stroke as a whole ends up no more
+	// opaque than its opacity
+	var area image.Rectangle
+	for _, d := range dabs {
+		area = area.Union(covered(tip, d))
+	}
+	buf := image.NewRGBA(area.Intersect(dst.Rect))
+	for _, d := range dabs {
+		if s.Erase {
+			stamp(buf, tip, d, color.RGBA{255, 255, 255, 255})
+		} else {
+			stamp(buf, tip, d, d.Color)
+		}
+	}
+
+	for y := buf.Rect.Min.Y; y < buf.Rect.Max.Y; y++ {
+		for x := buf.Rect.Min.X; x < buf.Rect.Max.X; x++ {
+			src := buf.Pix[buf.PixOffset(x, y):]
+			pix := dst.Pix[dst.PixOffset(x, y):]
+			sa := float64(src[3]) * s.Opacity
+			if sa == 0 {
+				continue
+			}
+
+			if s.Erase {
+				keep := 1 - sa/255
+				for c := 0; c < 4; c++ {
+					pix[c] = round(float64(pix[c]) * keep)
+				}
+				continue
+			}
+
+			inv := 1 - sa/255
+			for c := 0; c < 4; c++ {
+				pix[c] = round(float64(src[c])*s.Opacity + float64(pix[c])*inv)
+			}
+		}
+	}
+}
+
+// Stamp composites the brush tip named `tip`, stretched, rotated and tinted by `mask` as the dab
+// says, centered on the dab position
+func (r *Rasterizer) Stamp(dst *image.RGBA, tip string, d brush.Dab, mask color.RGBA) {
+	stamp(dst, r.tip(tip), d, mask)
+}
+
+// Erase removes as much coverage from `dst` as the brush tip named `tip` has at the dab
+// position, leaving transparency behind
+func (r *Rasterizer) Erase(dst *image.RGBA, tip string, d brush.Dab) {
+	erase(dst, r.tip(tip), d)
+}
+
+func stamp(dst *image.RGBA, tip *image.RGBA, d brush.Dab, mask color.RGBA) {
+	each(dst, tip, d, func(pix []uint8, sr float64, sg float64, sb float64, sa float64) {
+		// tint like a color mask, which multiplies every channel
+		sr = sr * float64(mask.R) / 255
+		sg = sg * float64(mask.G) / 255
+		sb = sb * float64(mask.B) / 255
+		sa = sa * float64(mask.A) / 255
+
+		inv := 1 - sa/255
+		pix[0] = round(sr + float64(pix[0])*inv)
+		pix[1] = round(sg + float64(pix[1])*inv)
+		pix[2] = round(sb + float64(pix[2])*inv)
+		pix[3] = round(sa + float64(pix[3])*inv)
+	})
+}
+
+func erase(dst *image.RGBA, tip *image.RGBA, d brush.Dab) {
+	each(dst, tip, d, func(pix []uint8, _ float64, _ float64, _ float64, sa float64) {
+		keep := 1 - sa/255
+		pix[0] = round(float64(pix[0]) * keep)
+		pix[1] = round(float64(pix[1]) * keep)
+		pix[2] = round(float64(pix[2]) * keep)
+		pix[3] = round(float64(pix[3]) * keep)
+	})
+}
+
+// covered returns the pixels that the dab may cover, however it is rotated
+func covered(tip *image.RGBA, d brush.Dab) image.Rectangle {
+	r := math.Hypot(float64(tip.Rect.Dx()), float64(tip.Rect.Dy())) * scale(tip, d) / 2
+	return image.Rect(
+		int(math.Floor(d.X-r)),
+		int(math.Floor(d.Y-r)),
+		int(math.Ceil(d.X+r)),
+		int(math.Ceil(d.Y+r)),
+	)
+}
+
+// scale returns how much `tip` is stretched to the size of the dab
+func scale(tip *image.RGBA, d brush.Dab) float64 {
+	return d.Size / float64(max(tip.Rect.Dx(), tip.Rect.Dy()))
+}
+
+// each calls `blend` with every pixel of `dst` the dab covers, along with the premultiplied
+// brush tip sampled there and faded by the dab's alpha
+func each(dst *image.RGBA, tip *image.RGBA, d brush.Dab, blend func(pix []uint8, sr float64, sg float64, sb float64, sa float64)) {
+	s := scale(tip, d)
+	if s <= 0 || d.Alpha <= 0 {
+		return
+	}
+
+	tw, th := float64(tip.Rect.Dx()), float64(tip.Rect.Dy())
+	area := covered(tip, d).Intersect(dst.Rect)
+
+	// turn pixels back by the angle of the dab to find them on the tip
+	sin, cos := math.Sincos(d.Angle)
+
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		// sample the tip at the pixel center
+		dy := float64(y) + 0.5 - d.Y
+		for x := area.Min.X; x < area.Max.X; x++ {
+			dx := float64(x) + 0.5 - d.X
+			u := (cos*dx+sin*dy)/s + tw/2
+			v := (cos*dy-sin*dx)/s + th/2
+
+			sr, sg, sb, sa := sample(tip, u, v)
+			if sa == 0 {
+				continue
+			}
+
+			i := dst.PixOffset(x, y)
+			blend(dst.Pix[i:i+4:i+4], sr*d.Alpha, sg*d.Alpha, sb*d.Alpha, sa*d.Alpha)
+		}
+	}
+}
+
+// sample bilinearly interpolates the premultiplied tip at `u`, `v`, outside of the tip is transparent
+func sample(tip *image.RGBA, u float64, v float64) (float64, float64, float64, float64) {
+	// texel centers are at .5
+	u -= 0.5
+	v -= 0.5
+
+	x0, y0 := int(math.Floor(u)), int(math.Floor(v))
+	fx, fy := u-float64(x0), v-float64(y0)
+
+	var c [4]float64
+	for _, t := range [4]struct {
+		x, y int
+		w    float64
+	}{
+		{x0, y0, (1 - fx) * (1 - fy)},
+		{x0 + 1, y0, fx * (1 - fy)},
+		{x0, y0 + 1, (1 - fx) * fy},
+		{x0 + 1, y0 + 1, fx * fy},
+	} {
+		if t.w == 0 || !(image.Point{t.x, t.y}.In(tip.Rect)) {
+			continue
+		}
+		i := tip.PixOffset(t.x, t.y)
+		c[0] += float64(tip.Pix[i]) * t.w
+		c[1] += float64(tip.Pix[i+1]) * t.w
+		c[2] += float64(tip.Pix[i+2]) * t.w
+		c[3] += float64(tip.Pix[i+3]) * t.w
+	}
+
+	return c[0], c[1], c[2], c[3]
+}
+
+func round(v float64) uint8 {
+	if v >= 255 {
+		return 255
+	}
+	if v <= 0 {
+		return 0
+	}
+	return uint8(v + 0.5)
+}
+
+func max(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,85 @@
+package raster
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/supermuesli/anim8/pkg/brush"
+	"github.com/supermuesli/anim8/pkg/scene"
+)
+
+var red = color.RGBA{255, 0, 0, 255}
+
+// line returns a stroke from left to right through the middle of a 64 x 64 image
+func line(preset string, width float64, seed int64) *scene.Stroke {
+	s := &scene.Stroke{Brush: preset, Opacity: 1, Seed: seed}
+	for x := 8.0; x <= 56; x += 4 {
+		s.Points = append(s.Points, scene.Point{X: x, Y: 32, Width: width, Alpha: 1, Color: red})
+	}
+	return s
+}
+
+func render(s ...*scene.Stroke) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	r := New(brush.Tip("hard"))
+	for _, s := range s {
+		r.Stroke(img, s)
+	}
+	return img
+}
+
+func TestStrokeDeterministic(t *testing.T) {
+	// chalk jitters size, angle, position and flow, all of it driven by the seed
+	a := render(line("Chalk", 12, 42))
+	b := render(line("Chalk", 12, 42))
+	if !bytes.Equal(a.Pix, b.Pix) {
+		t.Fatal("the same stroke and seed rendered different pixels")
+	}
+
+	c := render(line("Chalk", 12, 43))
+	if bytes.Equal(a.Pix, c.Pix) {
+		t.Fatal("another seed rendered the same jitter")
+	}
+}
+
+func TestStrokeColor(t *testing.T) {
+	img := render(line("Default", 12, 1))
+
+	if got := img.RGBAAt(32, 32); got != red {
+		t.Errorf("center of the stroke is %v, want %v", got, red)
+	}
+	if got := img.RGBAAt(32, 4); got.A != 0 {
+		t.Errorf("outside of the stroke is %v, want transparent", got)
+	}
+}
+
+func TestEraseClearsAlpha(t *testing.T) {
+	erase := line("Default", 20, 1)
+	erase.Erase = true
+	img := render(line("Default", 12, 1), erase)
+
+	for x := 16; x <= 48; x++ {
+		if a := img.RGBAAt(x, 32).A; a != 0 {
+			t.Fatalf("erased pixel at %d has alpha %d, want 0", x, a)
+		}
+	}
+}
+
+func TestLayerOpacity(t *testing.T) {
+	r := New(brush.Tip("hard"))
+	f := scene.NewFrame()
+	f.Layers[0].Strokes = []*scene.Stroke{line("Default", 12, 1)}
+	f.Layers[0].Opacity = 0.5
+
+	got := r.Flatten(f, 64, 64).RGBAAt(32, 32)
+	if got.A < 127 || got.A > 128 || got.R != got.A || got.G != 0 {
+		t.Errorf("stroke on a half opaque layer is %v, want half opaque red", got)
+	}
+
+	f.Layers[0].Hidden = true
+	if got := r.Flatten(f, 64, 64).RGBAAt(32, 32); got.A != 0 {
+		t.Errorf("stroke on a hidden layer is %v, want transparent", got)
+	}
+}
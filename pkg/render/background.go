@@ -0,0 +1,149 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	"math"
+	"os"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+	"golang.org/x/image/colornames"
+
+	"github.com/supermuesli/anim8/pkg/scene"
+)
+
+// the backgrounds B cycles through
+var backgrounds = []color.RGBA{
+	{0, 0, 0, 255},
+	{255, 255, 255, 255},
+	{},
+}
+
+// backdrop holds the sprites drawn behind the frames
+type backdrop struct {
+	// the background image `image` was made of
+	img   *image.RGBA
+	image *pixel.Sprite
+
+	// the bounds `checker` was made for, along with the size of its cells
+	checkerBounds pixel.Rect
+	checkerCell   int
+	checker       *pixel.Sprite
+}
+
+// drawBackground draws the background of the scene onto the document
+func (canvas *Canvas) drawBackground() {
+	bg := canvas.scene.Background
+	canvas.doc.Clear(bg.Color)
+
+	if bg.Image == nil {
+		return
+	}
+
+	if canvas.backdrop.img != bg.Image {
+		pic := pixel.PictureDataFromImage(bg.Image)
+		canvas.backdrop.img = bg.Image
+		canvas.backdrop.image = pixel.NewSprite(pic, pic.Bounds())
+	}
+
+	// stretch over the whole document
+	doc := canvas.doc.Bounds()
+	size := canvas.backdrop.image.Frame().Size()
+	canvas.backdrop.image.Draw(canvas.doc, pixel.IM.ScaledXY(pixel.ZV, pixel.V(doc.W()/size.X, doc.H()/size.Y)).Moved(doc.Center()))
+}
+
+// drawChecker draws a checkerboard into the window where the document is, so that transparency shows
+func (canvas *Canvas) drawChecker() {
+	doc := canvas.doc.Bounds()
+
+	// cells of about 8 pixels on screen, whatever the document is scaled to
+	cell := int(math.Max(1, math.Round(8/canvas.zoom())))
+
+	if canvas.backdrop.checker == nil || canvas.backdrop.checkerBounds != doc || canvas.backdrop.checkerCell != cell {
+		img := image.NewRGBA(image.Rect(0, 0, int(doc.W()), int(doc.H())))
+		draw.Draw(img, img.Bounds(), image.NewUniform(colornames.Lightgray), image.ZP, draw.Src)
+		dark := image.NewUniform(colornames.Darkgray)
+		for y := 0; y < img.Rect.Dy(); y += cell {
+			for x := (y / cell % 2) * cell; x < img.Rect.Dx(); x += 2 * cell {
+				draw.Draw(img, image.Rect(x, y, x+cell, y+cell), dark, image.ZP, draw.Src)
+			}
+		}
+
+		pic := pixel.PictureDataFromImage(img)
+		canvas.backdrop.checker = pixel.NewSprite(pic, pic.Bounds())
+		canvas.backdrop.checkerBounds = doc
+		canvas.backdrop.checkerCell = cell
+	}
+
+	canvas.backdrop.checker.Draw(canvas.Win, pixel.IM.Moved(doc.Center()).Chained(canvas.view))
+}
+
+// pollBackground handles the keys that change the background of the scene
+func (canvas *Canvas) pollBackground(ctrl bool, shift bool) {
+	if !canvas.Win.JustPressed(pixelgl.KeyB) {
+		return
+	}
+
+	bg := &canvas.scene.Background
+
+	switch {
+	// pick a background image at keypress CTRL+B, nothing removes it
+	case ctrl:
+		path := canvas.prompt("Background image ")
+		if path == "" {
+			bg.Image = nil
+			break
+		}
+
+		img, err := readImage(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			break
+		}
+		bg.Image = img
+
+	// type a background color at keypress SHIFT+B
+	case shift:
+		c, err := scene.ParseColor(canvas.prompt("Background color "))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			break
+		}
+		bg.Color = c
+
+	// cycle through black, white and transparent at keypress B
+	default:
+		next := 0
+		for i, c := range backgrounds {
+			if c == bg.Color {
+				next = (i + 1) % len(backgrounds)
+			}
+		}
+		bg.Color = backgrounds[next]
+	}
+
+	// every rendered frame has the old background
+	canvas.invalidateAll()
+}
+
+// readImage decodes the PNG or JPEG file at `path`
+func readImage(path string) (*image.RGBA, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return rgba, nil
+}
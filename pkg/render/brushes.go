@@ -0,0 +1,189 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+
+	"github.com/supermuesli/anim8/pkg/brush"
+	"github.com/supermuesli/anim8/pkg/scene"
+)
+
+// brushScale is how many document pixels across the brush is per step of the brush size
+const brushScale = 5.0
+
+// presetKeys select the brush presets, see brush.Presets
+var presetKeys = []pixelgl.Button{
+	pixelgl.Key1, pixelgl.Key2, pixelgl.Key3, pixelgl.Key4, pixelgl.Key5,
+	pixelgl.Key6, pixelgl.Key7, pixelgl.Key8, pixelgl.Key9,
+}
+
+// brushPreset returns the brush that new strokes are painted with
+func (canvas *Canvas) brushPreset() brush.Brush {
+	b := brush.Presets[canvas.preset]
+	if canvas.tip != "" {
+		b.Tip = canvas.tip
+	}
+	return b
+}
+
+// brushName describes the brush that new strokes are painted with
+func (canvas *Canvas) brushName() string {
+	b := canvas.brushPreset()
+	name := b.Name
+	if canvas.tip != "" {
+		name += " (" + canvas.tip + ")"
+	}
+	if canvas.erasing {
+		name = "Eraser, " + name
+	}
+	if canvas.selection.active {
+		name = "Select"
+	}
+	return name
+}
+
+// stabilizerName describes how strongly new strokes are smoothed
+func (canvas *Canvas) stabilizerName() string {
+	if !canvas.stabilizing {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%d", canvas.smoothing, brush.MaxStrength)
+}
+
+// onOff describes a setting that is either on or off
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+// selectPreset paints new strokes with the i-th preset, at the size it comes with
+func (canvas *Canvas) selectPreset(i int) {
+	canvas.preset = i
+	canvas.tip = ""
+	canvas.brushSize = brush.Presets[i].Size
+}
+
+// tipSprite returns the sprite of the tip named `name`, which is looked up in the scene and the
+// built-in tips, and falls back to the default tip
+func (canvas *Canvas) tipSprite(name string) *pixel.Sprite {
+	if name == "" {
+		return canvas.brush
+	}
+	if sprite, ok := canvas.tips[name]; ok {
+		return sprite
+	}
+
+	img, ok := canvas.scene.Tips[name]
+	if !ok {
+		if img = brush.Tip(name); img == nil {
+			return canvas.brush
+		}
+	}
+
+	pic := pixel.PictureDataFromImage(img)
+	sprite := pixel.NewSprite(pic, pic.Bounds())
+	canvas.tips[name] = sprite
+	return sprite
+}
+
+// tipScale returns the scale that stretches `sprite` to `size` pixels across
+func tipScale(sprite *pixel.Sprite, size float64) float64 {
+	bounds := sprite.Frame()
+	return size / math.Max(bounds.W(), bounds.H())
+}
+
+// useTip makes sure that the tip named `name` is part of the scene, so that strokes painted with
+// a built-in tip are saved and exported along with it
+func (canvas *Canvas) useTip(name string) {
+	if name == "" {
+		return
+	}
+	if _, ok := canvas.scene.Tips[name]; ok {
+		return
+	}
+	if img := brush.Tip(name); img != nil {
+		canvas.addTip(name, img)
+	}
+}
+
+// addTip adds the tip `img` named `name` to the scene, replacing the tip of that name
+func (canvas *Canvas) addTip(name string, img *image.RGBA) {
+	_, replaced := canvas.scene.Tips[name]
+
+	if canvas.scene.Tips == nil {
+		canvas.scene.Tips = make(map[string]*image.RGBA)
+	}
+	canvas.scene.Tips[name] = img
+	canvas.raster.SetTip(name, img)
+	delete(canvas.tips, name)
+
+	// strokes painted with the replaced tip look different now
+	if replaced {
+		canvas.invalidateAll()
+	}
+}
+
+// loadTip paints new strokes with the image file at `path` as tip, named after the file
+func (canvas *Canvas) loadTip(path string) error {
+	img, err := readImage(path)
+	if err != nil {
+		return err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	canvas.addTip(name, brush.Coverage(img))
+	canvas.tip = name
+	return nil
+}
+
+// pollBrushes handles the keys that pick the brush
+func (canvas *Canvas) pollBrushes(ctrl bool) {
+	// select a brush preset at keypress 1 to 9
+	for i, key := range presetKeys {
+		if !ctrl && i < len(brush.Presets) && canvas.Win.JustPressed(key) {
+			canvas.selectPreset(i)
+		}
+	}
+
+	// smooth new strokes with the stabilizer at keypress Q
+	if canvas.Win.JustPressed(pixelgl.KeyQ) {
+		canvas.stabilizing = !canvas.stabilizing
+	}
+
+	// simulate pressure from how fast strokes are painted at keypress V
+	if canvas.Win.JustPressed(pixelgl.KeyV) {
+		canvas.pressure = !canvas.pressure
+	}
+
+	// paint with a custom tip image at keypress CTRL+T
+	if ctrl && canvas.Win.JustPressed(pixelgl.KeyT) {
+		if name := canvas.prompt("Tip "); name != "" {
+			if err := canvas.loadTip(name); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+	}
+}
+
+// drawDab draws the dab `d` of `stroke` with the tip `sprite` onto `batch`
+func (canvas *Canvas) drawDab(batch *pixel.Batch, sprite *pixel.Sprite, stroke *scene.Stroke, d brush.Dab) {
+	// erasing only needs the coverage
+	mask := pixel.ToRGBA(d.Color)
+	if stroke.Erase {
+		mask = pixel.RGB(1, 1, 1)
+	}
+	batch.SetColorMask(mask.Scaled(d.Alpha))
+
+	// document coordinates point downwards, so clockwise there is clockwise on screen as well
+	matrix := pixel.IM.Scaled(pixel.ZV, tipScale(sprite, d.Size)).Rotated(pixel.ZV, -d.Angle)
+	sprite.Draw(batch, matrix.Moved(canvas.fromScene(d.X, d.Y)))
+}
@@ -0,0 +1,433 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/pixelgl"
+	"golang.org/x/image/colornames"
+
+	"github.com/supermuesli/anim8/pkg/palette"
+)
+
+// defaultPalette fills the palette panel until another palette is picked
+var defaultPalette = []color.RGBA{
+	{0, 0, 0, 255}, {29, 43, 83, 255}, {126, 37, 83, 255}, {0, 135, 81, 255},
+	{171, 82, 54, 255}, {95, 87, 79, 255}, {194, 195, 199, 255}, {255, 241, 232, 255},
+	{255, 0, 77, 255}, {255, 163, 0, 255}, {255, 236, 39, 255}, {0, 228, 54, 255},
+	{41, 173, 255, 255}, {131, 118, 156, 255}, {255, 119, 168, 255}, {255, 204, 170, 255},
+}
+
+// how many recently used colors are kept
+const recentColors = 8
+
+// what a click on the color panel started to change
+const (
+	pickNone = iota
+	pickSwatch
+	pickSV
+	pickHue
+)
+
+// colorState is the color the brush paints with, along with the colors to pick it from
+type colorState struct {
+	// foreground is the color of new strokes
+	foreground color.RGBA
+
+	// the foreground as hue in degrees, saturation and value, which keeps the hue of grays
+	hsv [3]float64
+
+	palette *palette.Palette
+	recent  []color.RGBA
+
+	// picker shows the HSV picker
+	picker bool
+
+	// what the mouse button currently held down picks, see pickNone
+	picking int
+}
+
+// newColorState starts out painting white with the default palette
+func newColorState() *colorState {
+	c := &colorState{palette: &palette.Palette{
+		Name:   "anim8",
+		Colors: append([]color.RGBA(nil), defaultPalette...),
+	}}
+	c.set(colornames.White)
+	return c
+}
+
+// set makes `c` the foreground color
+func (c *colorState) set(fg color.RGBA) {
+	c.foreground = fg
+	c.hsv[0], c.hsv[1], c.hsv[2] = toHSV(fg)
+}
+
+// setHSV makes the color with the given hue, saturation and value the foreground color
+func (c *colorState) setHSV(h float64, s float64, v float64) {
+	c.hsv = [3]float64{h, s, v}
+	c.foreground = fromHSV(h, s, v)
+}
+
+// selected returns the index of the foreground color in the palette, -1 if it isn't in there
+func (c *colorState) selected() int {
+	for i, s := range c.palette.Colors {
+		if s == c.foreground {
+			return i
+		}
+	}
+	return -1
+}
+
+// use remembers `fg` as the most recently used color
+func (c *colorState) use(fg color.RGBA) {
+	recent := []color.RGBA{fg}
+	for _, r := range c.recent {
+		if r != fg && len(recent) < recentColors {
+			recent = append(recent, r)
+		}
+	}
+	c.recent = recent
+}
+
+// colorPanel is where the parts of the color panel are in the window
+type colorPanel struct {
+	foreground pixel.Rect
+	swatches   []pixel.Rect
+	recent     []pixel.Rect
+	sv         pixel.Rect
+	hue        pixel.Rect
+}
+
+// colorPanel lays out the color panel along the left edge of the window
+func (canvas *Canvas) colorPanel() colorPanel {
+	const (
+		left  = 30.0
+		width = 188.0
+		gap   = 4.0
+	)
+
+	var panel colorPanel
+	top := canvas.height - 70
+	panel.foreground = pixel.R(left, top-40, left+40, top)
+	top -= 40 + 2*gap
+
+	// 8 swatches per row, more for large palettes so that they still fit
+	grid := func(n int) []pixel.Rect {
+		perRow := 8
+		for n > perRow*perRow && perRow < 32 {
+			perRow *= 2
+		}
+		g := gap * 8 / float64(perRow)
+		size := (width - float64(perRow-1)*g) / float64(perRow)
+		step := size + g
+
+		rects := make([]pixel.Rect, n)
+		for i := range rects {
+			x := left + float64(i%perRow)*step
+			y := top - float64(i/perRow)*step
+			rects[i] = pixel.R(x, y-size, x+size, y)
+		}
+		top -= float64((n+perRow-1)/perRow)*step + gap
+		return rects
+	}
+	panel.swatches = grid(len(canvas.colors.palette.Colors))
+	panel.recent = grid(len(canvas.colors.recent))
+
+	size := (width - 7*gap) / 8
+	panel.sv = pixel.R(left, top-width+size+gap, left+width-size-gap, top)
+	panel.hue = pixel.R(left+width-size, panel.sv.Min.Y, left+width, top)
+
+	return panel
+}
+
+// loadPalette replaces the palette with the palette file at `path`
+func (canvas *Canvas) loadPalette(path string) error {
+	p, err := palette.Load(path)
+	if err != nil {
+		return err
+	}
+
+	canvas.colors.palette = p
+	return nil
+}
+
+// savePalette writes the palette to the palette file at `path`
+func (canvas *Canvas) savePalette(path string) error {
+	return palette.Save(path, canvas.colors.palette)
+}
+
+// pollColors picks colors from the color panel and tells whether the mouse is busy doing so,
+// rather than painting
+func (canvas *Canvas) pollColors() bool {
+	colors := canvas.colors
+
+	// show or hide the HSV picker at keypress TAB
+	if canvas.Win.JustPressed(pixelgl.KeyTab) {
+		colors.picker = !colors.picker
+	}
+
+	// select the previous or next swatch at keypress COMMA, PERIOD
+	if n := len(colors.palette.Colors); n > 0 {
+		step := 0
+		if canvas.Win.JustPressed(pixelgl.KeyComma) || canvas.Win.Repeated(pixelgl.KeyComma) {
+			step = -1
+		}
+		if canvas.Win.JustPressed(pixelgl.KeyPeriod) || canvas.Win.Repeated(pixelgl.KeyPeriod) {
+			step = 1
+		}
+		if step != 0 {
+			i := colors.selected()
+			if i < 0 && step < 0 {
+				i = 0
+			}
+			colors.set(colors.palette.Colors[(i+step+n)%n])
+		}
+	}
+
+	// add the foreground color to the palette at keypress PLUS (=), remove it at keypress MINUS
+	if canvas.Win.JustPressed(pixelgl.KeyEqual) && colors.selected() < 0 {
+		colors.palette.Colors = append(colors.palette.Colors, colors.foreground)
+	}
+	if canvas.Win.JustPressed(pixelgl.KeyMinus) {
+		if i := colors.selected(); i >= 0 {
+			colors.palette.Colors = append(colors.palette.Colors[:i], colors.palette.Colors[i+1:]...)
+		}
+	}
+
+	if !canvas.Win.Pressed(pixelgl.MouseButtonLeft) {
+		colors.picking = pickNone
+		return false
+	}
+
+	mouse := canvas.Win.MousePosition()
+	panel := canvas.colorPanel()
+
+	if canvas.Win.JustPressed(pixelgl.MouseButtonLeft) {
+		pick := func(rects []pixel.Rect, swatches []color.RGBA) {
+			for i, r := range rects {
+				if r.Contains(mouse) {
+					colors.set(swatches[i])
+					colors.picking = pickSwatch
+				}
+			}
+		}
+		pick(panel.swatches, colors.palette.Colors)
+		pick(panel.recent, colors.recent)
+
+		if colors.picker && panel.sv.Contains(mouse) {
+			colors.picking = pickSV
+		}
+		if colors.picker && panel.hue.Contains(mouse) {
+			colors.picking = pickHue
+		}
+	}
+
+	// keep picking while the mouse is dragged, even beyond the panel
+	switch colors.picking {
+	case pickSV:
+		s := clamp((mouse.X-panel.sv.Min.X)/panel.sv.W(), 0, 1)
+		v := clamp((mouse.Y-panel.sv.Min.Y)/panel.sv.H(), 0, 1)
+		colors.setHSV(colors.hsv[0], s, v)
+	case pickHue:
+		h := clamp((mouse.Y-panel.hue.Min.Y)/panel.hue.H(), 0, 1) * 360
+		colors.setHSV(h, colors.hsv[1], colors.hsv[2])
+	}
+
+	return colors.picking != pickNone
+}
+
+// drawColors draws the color panel into the window
+func (canvas *Canvas) drawColors() {
+	colors := canvas.colors
+	panel := canvas.colorPanel()
+	imd := canvas.gui.colors
+	imd.Clear()
+
+	swatch(imd, panel.foreground, colors.foreground)
+	for i, r := range panel.swatches {
+		swatch(imd, r, colors.palette.Colors[i])
+
+		// the swatch painted with stands out
+		if colors.palette.Colors[i] == colors.foreground {
+			imd.Color = colornames.White
+			imd.Push(r.Min.Sub(pixel.V(2, 2)), r.Max.Add(pixel.V(2, 2)))
+			imd.Rectangle(2)
+		}
+	}
+	for i, r := range panel.recent {
+		swatch(imd, r, colors.recent[i])
+	}
+
+	if colors.picker {
+		// saturation grows to the right and value upwards, as a grid of gradients
+		const cells = 16
+		h := colors.hsv[0]
+		w, hh := panel.sv.W()/cells, panel.sv.H()/cells
+		for i := 0; i < cells; i++ {
+			for j := 0; j < cells; j++ {
+				s0, s1 := float64(i)/cells, float64(i+1)/cells
+				v0, v1 := float64(j)/cells, float64(j+1)/cells
+				min := panel.sv.Min.Add(pixel.V(float64(i)*w, float64(j)*hh))
+
+				imd.Color = fromHSV(h, s0, v0)
+				imd.Push(min)
+				imd.Color = fromHSV(h, s1, v0)
+				imd.Push(min.Add(pixel.V(w, 0)))
+				imd.Color = fromHSV(h, s1, v1)
+				imd.Push(min.Add(pixel.V(w, hh)))
+				imd.Color = fromHSV(h, s0, v1)
+				imd.Push(min.Add(pixel.V(0, hh)))
+				imd.Polygon(0)
+			}
+		}
+
+		// the hue bar is exact with one gradient per sixth of the hue circle
+		step := panel.hue.H() / 6
+		for i := 0; i < 6; i++ {
+			y := panel.hue.Min.Y + float64(i)*step
+			imd.Color = fromHSV(float64(i)*60, 1, 1)
+			imd.Push(pixel.V(panel.hue.Min.X, y), pixel.V(panel.hue.Max.X, y))
+			imd.Color = fromHSV(float64(i+1)*60, 1, 1)
+			imd.Push(pixel.V(panel.hue.Max.X, y+step), pixel.V(panel.hue.Min.X, y+step))
+			imd.Polygon(0)
+		}
+
+		// markers for the current color
+		sv := pixel.V(panel.sv.Min.X+colors.hsv[1]*panel.sv.W(), panel.sv.Min.Y+colors.hsv[2]*panel.sv.H())
+		imd.Color = colornames.Gray
+		imd.Push(sv)
+		imd.Circle(4, 2)
+
+		y := panel.hue.Min.Y + colors.hsv[0]/360*panel.hue.H()
+		imd.Push(pixel.V(panel.hue.Min.X-2, y-2), pixel.V(panel.hue.Max.X+2, y+2))
+		imd.Rectangle(2)
+	}
+
+	imd.Draw(canvas.Win)
+}
+
+// swatch draws a color swatch with a border
+func swatch(imd *imdraw.IMDraw, r pixel.Rect, c color.RGBA) {
+	imd.Color = c
+	imd.Push(r.Min, r.Max)
+	imd.Rectangle(0)
+
+	imd.Color = colornames.Gray
+	imd.Push(r.Min, r.Max)
+	imd.Rectangle(1)
+}
+
+// toHSV converts `c` into hue in degrees, saturation and value
+func toHSV(c color.RGBA) (float64, float64, float64) {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	max := math.Max(r, math.Max(g, b))
+	min := math.Min(r, math.Min(g, b))
+	d := max - min
+
+	h := 0.0
+	switch {
+	case d == 0:
+	case max == r:
+		h = 60 * math.Mod((g-b)/d+6, 6)
+	case max == g:
+		h = 60 * ((b-r)/d + 2)
+	default:
+		h = 60 * ((r-g)/d + 4)
+	}
+
+	s := 0.0
+	if max > 0 {
+		s = d / max
+	}
+
+	return h, s, max
+}
+
+// fromHSV converts hue in degrees, saturation and value into an opaque color
+func fromHSV(h float64, s float64, v float64) color.RGBA {
+	h = math.Mod(h, 360) / 60
+	c := v * s
+	x := c * (1 - math.Abs(math.Mod(h, 2)-1))
+
+	var r, g, b float64
+	switch int(h) {
+	case 0:
+		r, g, b = c, x, 0
+	case 1:
+		r, g, b = x, c, 0
+	case 2:
+		r, g, b = 0, c, x
+	case 3:
+		r, g, b = 0, x, c
+	case 4:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+
+	m := v - c
+	return color.RGBA{
+		uint8(math.Round((r + m) * 255)),
+		uint8(math.Round((g + m) * 255)),
+		uint8(math.Round((b + m) * 255)),
+		255,
+	}
+}
+
+func clamp(v float64, min float64, max float64) float64 {
+	return math.Max(min, math.Min(max, v))
+}
+
+// pollEyedropper picks the color under the mouse while ALT is held and tells whether the mouse is
+// busy doing so, rather than painting. It samples the current frame, just the current layer
+// with SHIFT, or a frame shown beneath the current one by the onion skin with CTRL: the
+// previous frame, or the next one with CTRL+SHIFT.
+func (canvas *Canvas) pollEyedropper() bool {
+	win := canvas.Win
+	if !win.Pressed(pixelgl.KeyLeftAlt) && !win.Pressed(pixelgl.KeyRightAlt) {
+		return false
+	}
+	if !win.Pressed(pixelgl.MouseButtonLeft) {
+		return false
+	}
+
+	x, y := canvas.toScene(canvas.fromWindow(win.MousePosition()))
+	pt := image.Pt(int(math.Floor(x)), int(math.Floor(y)))
+	if !pt.In(image.Rect(0, 0, canvas.scene.Width, canvas.scene.Height)) {
+		return true
+	}
+
+	cur := canvas.scene.Current
+	shift := win.Pressed(pixelgl.KeyLeftShift) || win.Pressed(pixelgl.KeyRightShift)
+	var img *image.RGBA
+	switch {
+	case win.Pressed(pixelgl.KeyLeftControl) || win.Pressed(pixelgl.KeyRightControl):
+		// the first frame has no previous frame, and the onion skin may only show next ones
+		o := canvas.onion
+		i := cur - 1
+		if shift || cur == 0 || o.enabled && o.before == 0 && o.after > 0 {
+			i = cur + 1
+		}
+		if i >= len(canvas.scene.Frames) {
+			return true
+		}
+		img = canvas.rendered(i)
+	case shift:
+		img = canvas.renderedLayer(cur, canvas.scene.CurrentLayer())
+	default:
+		img = canvas.rendered(cur)
+	}
+
+	// nothing to pick where it is transparent, brush colors are opaque
+	c := color.NRGBAModel.Convert(img.RGBAAt(pt.X, pt.Y)).(color.NRGBA)
+	if c.A == 0 {
+		return true
+	}
+	canvas.colors.set(color.RGBA{c.R, c.G, c.B, 255})
+
+	return true
+}
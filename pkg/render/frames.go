@@ -0,0 +1,238 @@
+package render
+
+import (
+	"image"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+
+	"github.com/supermuesli/anim8/pkg/brush"
+	"github.com/supermuesli/anim8/pkg/scene"
+)
+
+// frameCache holds what is drawn for a frame of the scene. Frames recorded in the history never
+// change, so the cache is keyed by the frame itself and rebuilt once a frame is edited.
+type frameCache struct {
+	layers []*layerCache
+
+	// the frame rendered without GUI, nil until it is needed
+	img *image.RGBA
+
+	// the frame scaled down for the timeline, nil until it is needed
+	thumb *pixelgl.Canvas
+}
+
+// layerCache holds what is drawn for a layer of a frame
+type layerCache struct {
+	// consecutive strokes that paint or erase alike with the same tip share a batch
+	runs     []strokeRun
+	underlay *pixel.Sprite
+
+	// the layer rendered on its own without GUI, nil until it is needed
+	img *image.RGBA
+}
+
+// strokeRun is a batch of strokes that are either all painted or all erased with the same tip
+type strokeRun struct {
+	batch *pixel.Batch
+	erase bool
+	tip   string
+
+	// a stroke that isn't opaque is drawn on its own, so that its opacity applies to it as a whole
+	alone *scene.Stroke
+}
+
+// cached returns the cache of `frame`, building it if there is none yet
+func (canvas *Canvas) cached(frame *scene.Frame) *frameCache {
+	if c, ok := canvas.cache[frame]; ok {
+		return c
+	}
+
+	c := &frameCache{layers: make([]*layerCache, len(frame.Layers))}
+	for i, layer := range frame.Layers {
+		lc := &layerCache{}
+		for _, stroke := range layer.Strokes {
+			canvas.drawStroke(canvas.batch(lc, stroke), stroke)
+		}
+
+		if layer.Image != nil {
+			pic := pixel.PictureDataFromImage(layer.Image)
+			lc.underlay = pixel.NewSprite(pic, pic.Bounds())
+		}
+
+		c.layers[i] = lc
+	}
+
+	canvas.cache[frame] = c
+	return c
+}
+
+// current returns the cache of the frame being edited
+func (canvas *Canvas) current() *frameCache {
+	return canvas.cached(canvas.scene.Frame())
+}
+
+// layer returns the cache of the layer being edited
+func (canvas *Canvas) layer() *layerCache {
+	return canvas.current().layers[canvas.scene.CurrentLayer()]
+}
+
+// rendered returns the i-th frame rendered without GUI
+func (canvas *Canvas) rendered(i int) *image.RGBA {
+	c := canvas.cached(canvas.scene.Frames[i])
+	if c.img == nil {
+		c.img = canvas.raster.Frame(canvas.scene, canvas.scene.Frames[i])
+	}
+	return c.img
+}
+
+// renderedLayer returns the layer at index `l` of the i-th frame rendered on its own without GUI
+func (canvas *Canvas) renderedLayer(i int, l int) *image.RGBA {
+	frame := canvas.scene.Frames[i]
+	lc := canvas.cached(frame).layers[l]
+	if lc.img == nil {
+		lc.img = canvas.raster.Layer(frame.Layers[l], canvas.scene.Width, canvas.scene.Height)
+	}
+	return lc.img
+}
+
+// animation renders the frames that make up the animation, see scene.Animation
+func (canvas *Canvas) animation() []*image.RGBA {
+	frames := make([]*image.RGBA, len(canvas.scene.Animation()))
+	for i := range frames {
+		frames[i] = canvas.rendered(i)
+	}
+	return frames
+}
+
+// edit returns the current frame ready to be changed. What was already drawn onto its batch is
+// kept, so strokes painted live don't need to be drawn again.
+func (canvas *Canvas) edit() *scene.Frame {
+	c := canvas.current()
+	delete(canvas.cache, canvas.scene.Frame())
+
+	frame := canvas.scene.Edit(canvas.scene.Current)
+	c.img = nil
+	c.thumb = nil
+	for _, lc := range c.layers {
+		lc.img = nil
+	}
+	canvas.cache[frame] = c
+	return frame
+}
+
+// invalidate drops the cache of `frame`, after it changed in a way that needs a full redraw
+func (canvas *Canvas) invalidate(frame *scene.Frame) {
+	delete(canvas.cache, frame)
+}
+
+// invalidateAll drops the caches of all frames, after the layers changed on every frame
+func (canvas *Canvas) invalidateAll() {
+	canvas.cache = make(map[*scene.Frame]*frameCache)
+}
+
+// prune drops the caches of frames that are no longer part of the scene
+func (canvas *Canvas) prune() {
+	if len(canvas.cache) <= len(canvas.scene.Frames) {
+		return
+	}
+
+	live := make(map[*scene.Frame]bool, len(canvas.scene.Frames))
+	for _, frame := range canvas.scene.Frames {
+		live[frame] = true
+	}
+	for frame := range canvas.cache {
+		if !live[frame] {
+			delete(canvas.cache, frame)
+		}
+	}
+}
+
+// drawFrame draws the visible layers of the i-th frame onto `dst`, which is the size of the document
+func (canvas *Canvas) drawFrame(dst *pixelgl.Canvas, i int) {
+	frame := canvas.scene.Frames[i]
+	c := canvas.cached(frame)
+	center := pixel.IM.Moved(canvas.doc.Bounds().Center())
+
+	for j, layer := range frame.Layers {
+		if layer.Hidden {
+			continue
+		}
+
+		// flatten the layer first, so that its opacity applies to the layer as a whole
+		canvas.layerBuffer.Clear(pixel.Alpha(0))
+		if c.layers[j].underlay != nil {
+			size := layer.Image.Bounds().Size()
+			pos := canvas.fromScene(float64(layer.Offset.X)+float64(size.X)/2, float64(layer.Offset.Y)+float64(size.Y)/2)
+			c.layers[j].underlay.Draw(canvas.layerBuffer, pixel.IM.Moved(pos))
+		}
+		for _, run := range c.layers[j].runs {
+			// erasing takes coverage away from what is on the layer so far
+			if run.erase {
+				canvas.layerBuffer.SetComposeMethod(pixel.ComposeRout)
+			} else {
+				canvas.layerBuffer.SetComposeMethod(pixel.ComposeOver)
+			}
+			if run.alone == nil {
+				run.batch.Draw(canvas.layerBuffer)
+				continue
+			}
+
+			canvas.strokeBuffer.Clear(pixel.Alpha(0))
+			run.batch.Draw(canvas.strokeBuffer)
+			canvas.strokeBuffer.DrawColorMask(canvas.layerBuffer, center, pixel.Alpha(run.alone.Opacity))
+		}
+		canvas.layerBuffer.SetComposeMethod(pixel.ComposeOver)
+
+		canvas.layerBuffer.DrawColorMask(dst, center, pixel.Alpha(layer.Opacity))
+	}
+}
+
+// batch returns the batch of `lc` to draw `stroke` onto, which is the last one if it holds
+// strokes alike
+func (canvas *Canvas) batch(lc *layerCache, stroke *scene.Stroke) *pixel.Batch {
+	if n := len(lc.runs); n > 0 {
+		last := lc.runs[n-1]
+		if last.alone == stroke || last.alone == nil && stroke.Opacity >= 1 && last.erase == stroke.Erase && last.tip == stroke.Tip {
+			return last.batch
+		}
+	}
+
+	run := strokeRun{
+		batch: pixel.NewBatch(&pixel.TrianglesData{}, canvas.tipSprite(stroke.Tip).Picture()),
+		erase: stroke.Erase,
+		tip:   stroke.Tip,
+	}
+	if stroke.Opacity < 1 {
+		run.alone = stroke
+	}
+	lc.runs = append(lc.runs, run)
+	return run.batch
+}
+
+// drawStroke renders a recorded stroke from its path onto `batch`
+func (canvas *Canvas) drawStroke(batch *pixel.Batch, stroke *scene.Stroke) {
+	sprite := canvas.tipSprite(stroke.Tip)
+	for _, d := range brush.Dabs(brush.Of(stroke), stroke.Points, stroke.Seed) {
+		canvas.drawDab(batch, sprite, stroke, d)
+	}
+}
+
+// change records the scene in the history before it gets changed
+func (canvas *Canvas) change() {
+	canvas.history.Push(canvas.scene)
+}
+
+// Undo reverts the last change to the scene
+func (canvas *Canvas) Undo() {
+	if canvas.history.Undo(canvas.scene) {
+		canvas.prune()
+	}
+}
+
+// Redo applies the last undone change to the scene again
+func (canvas *Canvas) Redo() {
+	if canvas.history.Redo(canvas.scene) {
+		canvas.prune()
+	}
+}
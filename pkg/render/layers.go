@@ -0,0 +1,149 @@
+package render
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/faiface/pixel/pixelgl"
+
+	"github.com/supermuesli/anim8/pkg/export"
+	"github.com/supermuesli/anim8/pkg/scene"
+)
+
+// pollLayers handles the keys of the layer panel. Layers are changed on every frame at once, see
+// scene.Layers.
+func (canvas *Canvas) pollLayers(shift bool) {
+	win := canvas.Win
+	cur := canvas.scene.CurrentLayer()
+	n := len(canvas.scene.Frame().Layers)
+
+	// select the layer above or below at keypress PAGEUP, PAGEDOWN
+	// and move it up or down with SHIFT
+	if win.JustPressed(pixelgl.KeyPageUp) && cur < n-1 {
+		if shift {
+			canvas.change()
+			canvas.scene.MoveLayer(cur, cur+1)
+			canvas.invalidateAll()
+		}
+		canvas.scene.Layer = cur + 1
+	}
+	if win.JustPressed(pixelgl.KeyPageDown) && cur > 0 {
+		if shift {
+			canvas.change()
+			canvas.scene.MoveLayer(cur, cur-1)
+			canvas.invalidateAll()
+		}
+		canvas.scene.Layer = cur - 1
+	}
+
+	// add a layer above the current one at keypress N
+	if win.JustPressed(pixelgl.KeyN) {
+		canvas.change()
+		canvas.scene.AddLayer(cur + 1)
+		canvas.scene.Layer = cur + 1
+		canvas.invalidateAll()
+	}
+
+	// delete the current layer at keypress SHIFT+DELETE, so that it isn't lost by accident
+	if shift && win.JustPressed(pixelgl.KeyDelete) {
+		canvas.change()
+		canvas.scene.RemoveLayer(cur)
+		canvas.scene.Layer = canvas.scene.CurrentLayer()
+		canvas.selection.stroke = nil
+		canvas.invalidateAll()
+	}
+
+	layer := canvas.scene.Frame().Layers[canvas.scene.CurrentLayer()]
+
+	// hide or show the current layer at keypress H
+	if win.JustPressed(pixelgl.KeyH) {
+		hidden := !layer.Hidden
+		canvas.setLayer(func(l *scene.Layer) { l.Hidden = hidden })
+	}
+
+	// lock or unlock the current layer at keypress K
+	if win.JustPressed(pixelgl.KeyK) {
+		locked := !layer.Locked
+		canvas.setLayer(func(l *scene.Layer) { l.Locked = locked })
+	}
+
+	// decrease or increase the opacity of the current layer at keypress [, ]
+	opacity := layer.Opacity
+	if win.JustPressed(pixelgl.KeyLeftBracket) || win.Repeated(pixelgl.KeyLeftBracket) {
+		opacity = math.Max(0, opacity-0.1)
+	}
+	if win.JustPressed(pixelgl.KeyRightBracket) || win.Repeated(pixelgl.KeyRightBracket) {
+		opacity = math.Min(1, opacity+0.1)
+	}
+	if opacity != layer.Opacity {
+		// everything changed while holding the key can be undone at once
+		if !canvas.fading {
+			canvas.change()
+			canvas.fading = true
+		}
+
+		// don't pile up rounding errors
+		opacity = math.Round(opacity*10) / 10
+		canvas.scene.SetLayer(canvas.scene.CurrentLayer(), func(l *scene.Layer) { l.Opacity = opacity })
+		canvas.invalidateAll()
+	}
+	if !win.Pressed(pixelgl.KeyLeftBracket) && !win.Pressed(pixelgl.KeyRightBracket) {
+		canvas.fading = false
+	}
+}
+
+// setLayer changes the settings of the current layer on every frame
+func (canvas *Canvas) setLayer(set func(l *scene.Layer)) {
+	canvas.change()
+	canvas.scene.SetLayer(canvas.scene.CurrentLayer(), set)
+	canvas.invalidateAll()
+}
+
+// writeLayers lists the layers of the current frame in the layer panel, the topmost first
+func (canvas *Canvas) writeLayers() {
+	layers := canvas.scene.Frame().Layers
+	cur := canvas.scene.CurrentLayer()
+
+	fmt.Fprintln(canvas.gui.layers, "Layers")
+	for i := len(layers) - 1; i >= 0; i-- {
+		l := layers[i]
+
+		marker := " "
+		if i == cur {
+			marker = ">"
+		}
+
+		flags := ""
+		if l.Hidden {
+			flags += " hidden"
+		}
+		if l.Locked {
+			flags += " locked"
+		}
+
+		fmt.Fprintf(canvas.gui.layers, "%s %s\t%3.0f%%%s\n", marker, l.Name, l.Opacity*100, flags)
+	}
+}
+
+// DumpLayers saves every visible layer of the animation as its own set of PNGs into the directory `sceneName`
+func (canvas *Canvas) DumpLayers(sceneName string) error {
+	if err := os.MkdirAll(sceneName, 0700); err != nil {
+		return err
+	}
+
+	frames := canvas.scene.Animation()
+	for i, l := range canvas.scene.Frame().Layers {
+		if l.Hidden {
+			continue
+		}
+
+		// a PNG per tick, like Dump
+		imgs := canvas.raster.Layers(frames, i, canvas.scene.Width, canvas.scene.Height)
+		imgs = export.Held(imgs, scene.Holds(frames))
+		if err := export.PNGs(sceneName, export.LayerName(sceneName, l.Name), imgs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
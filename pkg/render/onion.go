@@ -0,0 +1,204 @@
+package render
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/pixelgl"
+
+	"github.com/supermuesli/anim8/pkg/project"
+	"github.com/supermuesli/anim8/pkg/scene"
+)
+
+// maxOnion is how many frames the onion skin shows at most in either direction
+const maxOnion = 5
+
+// onionStep is how much the opacity and falloff of the onion skin change per keypress
+const onionStep = 0.1
+
+// previous frames are tinted red and next frames green by default, to tell which way things move
+var (
+	defaultTintBefore = color.RGBA{255, 64, 64, 255}
+	defaultTintAfter  = color.RGBA{64, 220, 64, 255}
+)
+
+// onionState is how the frames around the current one are shown beneath it, as an aid for
+// drawing. They are drawn from the cache every time, so they never lag behind the scene.
+type onionState struct {
+	enabled bool
+
+	// how many previous and next frames are shown
+	before int
+	after  int
+
+	// opacity of the frames next to the current one, every frame further away is shown with
+	// `falloff` times as much as the one before
+	opacity float64
+	falloff float64
+
+	// the colors previous and next frames are shown in
+	tintBefore color.RGBA
+	tintAfter  color.RGBA
+
+	// every frame is flattened in here and tinted before it is drawn beneath the current one
+	buffer *pixelgl.Canvas
+	fill   *imdraw.IMDraw
+}
+
+// newOnionState shows the previous frame dimmed to 30%, for a document of the size `bounds`
+func newOnionState(bounds pixel.Rect) *onionState {
+	return &onionState{
+		enabled:    true,
+		before:     1,
+		after:      0,
+		opacity:    0.3,
+		falloff:    0.6,
+		tintBefore: defaultTintBefore,
+		tintAfter:  defaultTintAfter,
+		buffer:     pixelgl.NewCanvas(bounds),
+		fill:       imdraw.New(nil),
+	}
+}
+
+// settings returns the onion skin settings to save with the project
+func (o *onionState) settings() *project.Onion {
+	return &project.Onion{
+		Enabled:    o.enabled,
+		Before:     o.before,
+		After:      o.after,
+		Opacity:    o.opacity,
+		Falloff:    o.falloff,
+		TintBefore: scene.FormatColor(o.tintBefore),
+		TintAfter:  scene.FormatColor(o.tintAfter),
+	}
+}
+
+// restore applies the onion skin settings saved with a project, older projects keep the current ones
+func (o *onionState) restore(s *project.Onion) {
+	if s == nil {
+		return
+	}
+	o.enabled = s.Enabled
+	o.before = clampInt(s.Before, 0, maxOnion)
+	o.after = clampInt(s.After, 0, maxOnion)
+	if s.Opacity > 0 && s.Opacity <= 1 {
+		o.opacity = s.Opacity
+	}
+	if s.Falloff > 0 && s.Falloff <= 1 {
+		o.falloff = s.Falloff
+	}
+	if c, err := scene.ParseColor(s.TintBefore); err == nil && s.TintBefore != "" {
+		o.tintBefore = c
+	}
+	if c, err := scene.ParseColor(s.TintAfter); err == nil && s.TintAfter != "" {
+		o.tintAfter = c
+	}
+}
+
+// pollOnion handles the keys of the onion skin
+func (canvas *Canvas) pollOnion(ctrl bool, shift bool) {
+	win := canvas.Win
+	o := canvas.onion
+
+	// show or hide the onion skin at keypress O
+	if !ctrl && win.JustPressed(pixelgl.KeyO) {
+		o.enabled = !o.enabled
+	}
+
+	// show one more previous or next frame at keypress U, I, and one less with SHIFT
+	step := 1
+	if shift {
+		step = -1
+	}
+	if !ctrl && win.JustPressed(pixelgl.KeyU) {
+		o.before = clampInt(o.before+step, 0, maxOnion)
+	}
+	if !ctrl && win.JustPressed(pixelgl.KeyI) {
+		o.after = clampInt(o.after+step, 0, maxOnion)
+	}
+
+	// show the onion skin more opaque at keypress CTRL+U, and fade the frames further away less
+	// at keypress CTRL+I, the other way around with SHIFT
+	if ctrl && (win.JustPressed(pixelgl.KeyU) || win.Repeated(pixelgl.KeyU)) {
+		o.opacity = clamp(o.opacity+float64(step)*onionStep, onionStep, 1)
+	}
+	if ctrl && (win.JustPressed(pixelgl.KeyI) || win.Repeated(pixelgl.KeyI)) {
+		o.falloff = clamp(o.falloff+float64(step)*onionStep, onionStep, 1)
+	}
+
+	// tint the previous frames with the current color at keypress J, the next frames with SHIFT,
+	// and go back to red and green with CTRL
+	if win.JustPressed(pixelgl.KeyJ) {
+		switch {
+		case ctrl:
+			o.tintBefore, o.tintAfter = defaultTintBefore, defaultTintAfter
+		case shift:
+			o.tintAfter = canvas.colors.foreground
+		default:
+			o.tintBefore = canvas.colors.foreground
+		}
+	}
+}
+
+// onionName describes which frames the onion skin shows
+func (canvas *Canvas) onionName() string {
+	o := canvas.onion
+	if !o.enabled || o.before == 0 && o.after == 0 {
+		return "off"
+	}
+	return fmt.Sprintf("-%d +%d %.0f%% x%.1f", o.before, o.after, o.opacity*100, o.falloff)
+}
+
+// drawOnion draws the frames around the current one tinted and faded onto the document, the
+// nearest ones on top
+func (canvas *Canvas) drawOnion() {
+	o := canvas.onion
+	if !o.enabled {
+		return
+	}
+
+	cur := canvas.scene.Current
+	for d := maxOnion; d >= 1; d-- {
+		alpha := o.opacity
+		for i := 1; i < d; i++ {
+			alpha *= o.falloff
+		}
+
+		if d <= o.after && cur+d < len(canvas.scene.Frames) {
+			canvas.drawTinted(cur+d, o.tintAfter, alpha)
+		}
+		if d <= o.before && cur-d >= 0 {
+			canvas.drawTinted(cur-d, o.tintBefore, alpha)
+		}
+	}
+}
+
+// drawTinted draws the i-th frame recolored with `tint` onto the document, with the opacity `alpha`
+func (canvas *Canvas) drawTinted(i int, tint color.RGBA, alpha float64) {
+	o := canvas.onion
+	o.buffer.Clear(pixel.Alpha(0))
+	canvas.drawFrame(o.buffer, i)
+
+	// keep the coverage of the frame, but paint it with the tint
+	o.buffer.SetComposeMethod(pixel.ComposeIn)
+	o.fill.Clear()
+	o.fill.Color = tint
+	o.fill.Push(o.buffer.Bounds().Min, o.buffer.Bounds().Max)
+	o.fill.Rectangle(0)
+	o.fill.Draw(o.buffer)
+	o.buffer.SetComposeMethod(pixel.ComposeOver)
+
+	o.buffer.DrawColorMask(canvas.doc, pixel.IM.Moved(canvas.doc.Bounds().Center()), pixel.Alpha(alpha))
+}
+
+func clampInt(v int, min int, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,149 @@
+package render
+
+import (
+	"time"
+
+	"github.com/faiface/pixel/pixelgl"
+
+	"github.com/supermuesli/anim8/pkg/scene"
+)
+
+// names of the play modes, see scene.PlayOnce
+var playModes = []string{"once", "loop", "ping-pong"}
+
+// minPlaybackFPS is the slowest the playback can be turned down to
+const minPlaybackFPS = 5
+
+// playbackState plays the animation within the main loop, timed by the clock
+type playbackState struct {
+	*scene.Playback
+
+	// when the playhead was last moved along
+	last time.Time
+}
+
+// newPlaybackState loops over the whole animation
+func newPlaybackState() *playbackState {
+	return &playbackState{Playback: scene.NewPlayback()}
+}
+
+// playRange returns the first and last frame played, limited to the frames of the animation
+func (canvas *Canvas) playRange() (int, int) {
+	return canvas.playback.Range(canvas.scene)
+}
+
+// play starts playing, see scene.Playback.Play
+func (canvas *Canvas) play() {
+	canvas.playback.Play(canvas.scene)
+	canvas.playback.last = time.Now()
+}
+
+// pause stops playing at the current frame
+func (canvas *Canvas) pause() {
+	canvas.playback.Pause()
+}
+
+// stop stops playing and goes back to the start of the play range
+func (canvas *Canvas) stop() {
+	canvas.playback.Stop(canvas.scene)
+}
+
+// resetPlayback stops playing and plays the whole animation again, for when the frames are
+// replaced by others that the play range doesn't fit
+func (canvas *Canvas) resetPlayback() {
+	canvas.playback.Reset()
+}
+
+// advance moves the playhead along by the time that passed until `now`
+func (canvas *Canvas) advance(now time.Time) {
+	p := canvas.playback
+	if !p.Playing {
+		return
+	}
+
+	// don't rush through the frames after the window was busy otherwise, e.g. with a prompt
+	d := now.Sub(p.last)
+	if d >= time.Second {
+		d = 0
+	}
+	p.last = now
+
+	p.Advance(canvas.scene, d)
+}
+
+// playbackName describes how the animation is played
+func (canvas *Canvas) playbackName() string {
+	p := canvas.playback
+
+	name := playModes[p.Mode]
+	if p.Reverse {
+		name += " reverse"
+	}
+	if p.Playing {
+		return "playing " + name
+	}
+	return name
+}
+
+// pollPlayback handles the transport keys and moves the playhead along while playing
+func (canvas *Canvas) pollPlayback(ctrl bool, shift bool) {
+	win := canvas.Win
+	p := canvas.playback
+
+	// play or pause at keypress P, stop and go back to the start with SHIFT
+	if !ctrl && win.JustPressed(pixelgl.KeyP) {
+		switch {
+		case shift:
+			canvas.stop()
+		case p.Playing:
+			canvas.pause()
+		default:
+			canvas.play()
+		}
+	}
+
+	// play once, in a loop or ping-pong at keypress L, and play backwards or forwards with SHIFT
+	if win.JustPressed(pixelgl.KeyL) {
+		if shift {
+			p.Reverse = !p.Reverse
+		} else {
+			p.Mode = (p.Mode + 1) % len(playModes)
+		}
+	}
+
+	// start the play range at the current frame at keypress M (mark), end it there with SHIFT,
+	// and play everything again with CTRL
+	if win.JustPressed(pixelgl.KeyM) {
+		cur := canvas.scene.Current
+		switch {
+		case ctrl:
+			p.In, p.Out = -1, -1
+		case shift:
+			p.Out = cur
+			if p.In > cur {
+				p.In = -1
+			}
+		default:
+			p.In = cur
+			if p.Out >= 0 && p.Out < cur {
+				p.Out = -1
+			}
+		}
+	}
+
+	// increase or decrease the playback FPS while playing at keypress UP, DOWN, unless they
+	// reorder the selected stroke
+	if p.Playing && !shift && canvas.selection.stroke == nil {
+		if win.JustPressed(pixelgl.KeyUp) || win.Repeated(pixelgl.KeyUp) {
+			canvas.scene.FPS++
+		}
+		if win.JustPressed(pixelgl.KeyDown) || win.Repeated(pixelgl.KeyDown) {
+			canvas.scene.FPS--
+			if canvas.scene.FPS < minPlaybackFPS {
+				canvas.scene.FPS = minPlaybackFPS
+			}
+		}
+	}
+
+	canvas.advance(time.Now())
+}
@@ -0,0 +1,81 @@
+package render
+
+import (
+	"github.com/faiface/pixel"
+
+	"github.com/supermuesli/anim8/pkg/brush"
+	"github.com/supermuesli/anim8/pkg/project"
+	"github.com/supermuesli/anim8/pkg/scene"
+)
+
+// Project returns the scene along with the brush settings
+func (canvas *Canvas) Project() *project.Project {
+	return &project.Project{
+		Scene: canvas.scene,
+		Brush: project.Brush{
+			Size:    canvas.brushSize,
+			Erasing: canvas.erasing,
+			Color:   scene.FormatColor(canvas.colors.foreground),
+			Preset:  brush.Presets[canvas.preset].Name,
+			Tip:     canvas.tip,
+
+			Smoothing:   canvas.smoothing,
+			Stabilizing: canvas.stabilizing,
+			Pressure:    canvas.pressure,
+		},
+		Onion: canvas.onion.settings(),
+	}
+}
+
+// Save writes the scene as a project file to `path`
+func (canvas *Canvas) Save(path string) error {
+	return project.Save(path, canvas.Project())
+}
+
+// Load replaces the scene with the project file at `path`
+func (canvas *Canvas) Load(path string) error {
+	p, err := project.Load(path)
+	if err != nil {
+		return err
+	}
+
+	canvas.Open(p)
+	return nil
+}
+
+// Open replaces the scene with the given project
+func (canvas *Canvas) Open(p *project.Project) {
+	canvas.scene = p.Scene
+	canvas.doc.SetBounds(pixel.R(0, 0, float64(p.Scene.Width), float64(p.Scene.Height)))
+	canvas.layerBuffer.SetBounds(canvas.doc.Bounds())
+	canvas.strokeBuffer.SetBounds(canvas.doc.Bounds())
+	canvas.onion.buffer.SetBounds(canvas.doc.Bounds())
+	canvas.timeline.buffer.SetBounds(canvas.doc.Bounds())
+	canvas.cache = make(map[*scene.Frame]*frameCache)
+	canvas.tips = make(map[string]*pixel.Sprite)
+	canvas.raster.SetTips(canvas.scene)
+	canvas.history.Clear()
+	canvas.resetPlayback()
+	canvas.selection.stroke = nil
+
+	canvas.preset = brush.Preset(p.Brush.Preset)
+	canvas.tip = p.Brush.Tip
+	if p.Brush.Size >= 1 {
+		canvas.brushSize = p.Brush.Size
+	}
+	canvas.erasing = p.Brush.Erasing
+	if p.Brush.Smoothing >= 1 {
+		canvas.smoothing = p.Brush.Smoothing
+	}
+	canvas.stabilizing = p.Brush.Stabilizing
+	canvas.pressure = p.Brush.Pressure
+	if c, err := scene.ParseColor(p.Brush.Color); err == nil && p.Brush.Color != "" {
+		canvas.colors.set(c)
+	}
+
+	canvas.onion.restore(p.Onion)
+
+	if canvas.scene.Name != "" {
+		canvas.Win.SetTitle(canvas.title + " - " + canvas.scene.Name)
+	}
+}
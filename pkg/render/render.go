@@ -2,30 +2,25 @@ package render
 
 import (
 	"fmt"
+	"image/color"
+	"math"
 	"time"
 	"os"
-	"image"
-	"image/png"
+	"strings"
 
 	"github.com/faiface/pixel"
 	"github.com/faiface/pixel/text"
+	"github.com/faiface/pixel/imdraw"
 	"github.com/faiface/pixel/pixelgl"
 	"golang.org/x/image/colornames"
 	"golang.org/x/image/font/basicfont"
-)
-
-type vec struct {
-	x int
-	y int
-}
 
-func iVec(v pixel.Vec) vec {
-	return vec{int(v.X), int(v.Y)}
-}
-
-func fVec(v vec) pixel.Vec {
-	return pixel.Vec{float64(v.x), float64(v.y)}
-}
+	"github.com/supermuesli/anim8/pkg/brush"
+	"github.com/supermuesli/anim8/pkg/export"
+	"github.com/supermuesli/anim8/pkg/project"
+	"github.com/supermuesli/anim8/pkg/raster"
+	"github.com/supermuesli/anim8/pkg/scene"
+)
 
 // GUI 
 type GUI struct {
@@ -34,7 +29,9 @@ type GUI struct {
 	frameNr *text.Text
 	sceneName *text.Text
 	playbackFPS *text.Text
-	brushBatch *pixel.Batch
+	layers *text.Text
+	colors *imdraw.IMDraw
+	selection *imdraw.IMDraw
 }
 
 // Canvas 
@@ -50,33 +47,71 @@ type Canvas struct {
 
 	// set FPS
 	FPS <-chan time.Time
-	playbackFPS int
+
+	// the document being edited, see package scene
+	scene *scene.Scene
+	stroke *scene.Stroke
+	pen *brush.Pen
+	stroker *brush.Stroker
+	stabilizer *brush.Stabilizer
+
+	// when the stroke was last painted, to tell how fast the mouse moves
+	painted time.Time
+
+	// the document is drawn at its own resolution, then scaled into the window by `view`
+	doc *pixelgl.Canvas
+	view pixel.Matrix
+
+	// every layer is flattened in here before it is drawn onto the document
+	layerBuffer *pixelgl.Canvas
+
+	// strokes that aren't opaque are drawn in here before they are drawn onto their layer
+	strokeBuffer *pixelgl.Canvas
 
 	// batch/sprite attributes
-	spritesheet pixel.Picture
-	batch *pixel.Batch
-	batches []*pixel.Batch
-	curBatch int
+	cache map[*scene.Frame]*frameCache
 	brush *pixel.Sprite
-	brushBuffer map[pixel.Vec]float64
+
+	// sprites of the tips other than the default one by name, see scene.Tips
+	tips map[string]*pixel.Sprite
 	
 	// painting/polling/framebuffer attributes
-	frames [][]uint8
-	decay []uint8
-	snapshots []pixel.Batch
-	curSnapShot int
+	raster *raster.Rasterizer
+	onion *onionState
+	timeline *timelineState
+	playback *playbackState
+	backdrop *backdrop
+	history *scene.History
 
 	// canvas attributes
 	erasing bool
+	shifting bool
+
+	// fading while the opacity of the current layer is changed, so that it can be undone at once
+	fading bool
 
 	// brush attributes
 	brushSize float64
+	colors *colorState
 
+	// the index of the brush preset and the name of the tip replacing its own, see brush.Presets
+	preset int
+	tip string
 
+	// smoothing is the strength of the stabilizer, which only smooths strokes while stabilizing
+	smoothing int
+	stabilizing bool
+
+	// pressure is simulated from how fast strokes are painted, as far as the brush preset says so
+	pressure bool
+
+	// the stroke picked with the select tool
+	selection *selectState
 }
 
-// NewCanvas prepares a new Canvas
-func NewCanvas(width float64, height float64, brushFile []byte, fontFile []byte) *Canvas {
+// NewCanvas prepares a new Canvas in a `width` x `height` window, editing a document of
+// `docWidth` x `docHeight` pixels
+func NewCanvas(width float64, height float64, docWidth int, docHeight int, brushFile []byte, fontFile []byte) *Canvas {
 	cfg := pixelgl.WindowConfig {
 		Title:  "anim8",
 		Bounds: pixel.R(0, 0, width, height),
@@ -91,11 +126,12 @@ func NewCanvas(width float64, height float64, brushFile []byte, fontFile []byte)
 	win.Canvas().SetSmooth(true)
 	win.SetCursorVisible(false)
 
-	// brush spritesheet
-	spritesheet, err := loadPicture(brushFile)
+	// brush tip
+	tip, err := loadImage(brushFile)
 	if err != nil {
 		panic(err)
 	}
+	tipPicture := pixel.PictureDataFromImage(tip)
 
 	// gui
 	face, err := loadTTF(fontFile, 52)
@@ -112,12 +148,13 @@ func NewCanvas(width float64, height float64, brushFile []byte, fontFile []byte)
 		text.New(pixel.V(width/2 - 50, 20), textAtlas),
 		text.New(pixel.V(width/2 - 350, height/2), screenNameAtlas),
 		text.New(pixel.V(30, height - 30), textAtlas),
-		pixel.NewBatch(&pixel.TrianglesData{}, spritesheet),
+		text.New(pixel.V(width - 250, height - 150), textAtlas),
+		imdraw.New(nil),
+		imdraw.New(nil),
 	}
 
 
-	batch := pixel.NewBatch(&pixel.TrianglesData{}, spritesheet)
-	brush := pixel.NewSprite(spritesheet, spritesheet.Bounds())
+	tipSprite := pixel.NewSprite(tipPicture, tipPicture.Bounds())
 
 	canvas := Canvas {
 		win,
@@ -126,348 +163,572 @@ func NewCanvas(width float64, height float64, brushFile []byte, fontFile []byte)
 		height,
 		gui,
 		time.Tick(time.Second / 120),
-		15,
-		spritesheet,
-		batch,
-		[]*pixel.Batch{batch},
-		0,
-		brush,
-		make(map[pixel.Vec]float64),
-		[][]uint8{},
+		scene.New("", docWidth, docHeight),
 		nil,
-		[]pixel.Batch{*batch},
+		nil,
+		nil,
+		nil,
+		time.Time{},
+		pixelgl.NewCanvas(pixel.R(0, 0, float64(docWidth), float64(docHeight))),
+		pixel.IM,
+		pixelgl.NewCanvas(pixel.R(0, 0, float64(docWidth), float64(docHeight))),
+		pixelgl.NewCanvas(pixel.R(0, 0, float64(docWidth), float64(docHeight))),
+		make(map[*scene.Frame]*frameCache),
+		tipSprite,
+		make(map[string]*pixel.Sprite),
+		raster.New(tip),
+		newOnionState(pixel.R(0, 0, float64(docWidth), float64(docHeight))),
+		newTimelineState(pixel.R(0, 0, float64(docWidth), float64(docHeight))),
+		newPlaybackState(),
+		&backdrop{},
+		scene.NewHistory(scene.DefaultHistoryLimit),
+		false,
+		false,
+		false,
+		brush.Presets[0].Size,
+		newColorState(),
 		0,
+		"",
+		brush.MaxStrength / 2,
+		false,
 		false,
-		1,
+		&selectState{},
 	}
 
 	canvas.gui.brush.Color = colornames.Red
 	canvas.gui.frameNr.Color = colornames.Red
 	canvas.gui.sceneName.Color = colornames.Red
 	canvas.gui.playbackFPS.Color = colornames.Red
-	canvas.gui.brushBatch.SetColorMask(colornames.Gray)
+	canvas.gui.layers.Color = colornames.Red
+
+	canvas.doc.SetSmooth(true)
+	canvas.fit()
 
 	return &canvas
 }
 
-func (canvas *Canvas) snapshot() {
-	canvas.snapshots = append(canvas.snapshots, *canvas.batch)
+// fit scales the document as large as it fits into the window above the timeline, centered
+// with bars on the sides
+func (canvas *Canvas) fit() {
+	win := canvas.Win.Bounds()
+	win.Min.Y = timelineTop
+	doc := canvas.doc.Bounds()
+
+	zoom := math.Min(win.W()/doc.W(), win.H()/doc.H())
+	canvas.view = pixel.IM.Moved(doc.Center().Scaled(-1)).Scaled(pixel.ZV, zoom).Moved(win.Center())
 }
 
-// Paint draws or erases at the mouseposition
-func (canvas *Canvas) Paint(now pixel.Vec, prev pixel.Vec) {
-	// first draw as usual
-	canvas.brush.Draw(canvas.batch, pixel.IM.Scaled(pixel.ZV, canvas.brushSize/20).Moved(now))
+// fromWindow converts a window position into a position on the document's framebuffer
+func (canvas *Canvas) fromWindow(v pixel.Vec) pixel.Vec {
+	return canvas.view.Unproject(v)
+}
 
-	// delta
-	d := now.Sub(prev)
+// toScene converts a position on the document's framebuffer into document coordinates
+func (canvas *Canvas) toScene(v pixel.Vec) (float64, float64) {
+	return v.X, float64(canvas.scene.Height) - v.Y
+}
 
-	// how many brush's would fit into the distance that the mouse moved since the last tick
-	points := d.Len()/(4*canvas.brushSize/20)
-	if points > 16.0 {
-		points = 16.0
+// fromScene converts document coordinates into a position on the document's framebuffer
+func (canvas *Canvas) fromScene(x float64, y float64) pixel.Vec {
+	return pixel.V(x, float64(canvas.scene.Height)-y)
+}
+
+// zoom is how many window pixels across a document pixel is shown
+func (canvas *Canvas) zoom() float64 {
+	return canvas.view.Project(pixel.V(1, 0)).Sub(canvas.view.Project(pixel.ZV)).Len()
+}
+
+// show draws the document's framebuffer into the window
+func (canvas *Canvas) show() {
+	canvas.Win.Clear(colornames.Dimgray)
+	if canvas.scene.Background.Color.A < 255 {
+		canvas.drawChecker()
 	}
+	canvas.doc.Draw(canvas.Win, pixel.IM.Moved(canvas.doc.Bounds().Center()).Chained(canvas.view))
+}
 
-	// how many brush strokes we want to fit into that distance
-	strokes := 3.0
-	
-	// scaled delta
-	delta := d.Scaled(1/(strokes*points))
+// editable tells whether the current layer can be painted on
+func (canvas *Canvas) editable() bool {
+	layer := canvas.scene.Frame().Layers[canvas.scene.CurrentLayer()]
+	return !layer.Hidden && !layer.Locked
+}
 
-	// don't malloc a pixel.V every time
-	paintPos := prev
+// beginStroke starts recording a new stroke on the current layer
+func (canvas *Canvas) beginStroke() {
+	canvas.change()
+
+	b := canvas.brushPreset()
+	canvas.useTip(b.Tip)
+	canvas.stroke = &scene.Stroke{
+		Erase:   canvas.erasing,
+		Brush:   b.Name,
+		Tip:     b.Tip,
+		Opacity: b.Opacity,
+		Seed:    time.Now().UnixNano(),
+	}
+
+	c := color.RGBA{}
+	if !canvas.erasing {
+		c = canvas.colors.foreground
+	}
+	if !canvas.pressure {
+		b = b.Steady()
+	}
+	canvas.pen = brush.NewPen(b, canvas.brushSize*brushScale, c)
+	canvas.stroker = brush.NewStroker(brush.Of(canvas.stroke), canvas.stroke.Seed)
 
-	for i := float64(0); i < strokes*points; i = i+1 {
-		paintPos = paintPos.Add(delta)
-		canvas.brush.Draw(canvas.batch, pixel.IM.Scaled(pixel.ZV, canvas.brushSize/20).Moved(paintPos))
+	strength := 0
+	if canvas.stabilizing {
+		strength = canvas.smoothing
 	}
+	canvas.stabilizer = brush.NewStabilizer(strength)
 }
 
-// BrustType returns the string corresponding to the current brush type
-func (canvas *Canvas) BrushType() string {
-	if canvas.erasing {
-		return "Erasor"
+// endStroke adds the recorded stroke to the current layer
+func (canvas *Canvas) endStroke() {
+	if canvas.stroke == nil {
+		return
+	}
+
+	// the stroke catches up with where the mouse button was released
+	for _, p := range canvas.stabilizer.Finish() {
+		canvas.strokeTo(p)
 	}
 
-	return "Default Brush"
+	// the end of the stroke tapers off, now that it is known where it is, which needs it drawn again
+	tapered := canvas.pen.End(canvas.stroke.Points)
+
+	frame := canvas.edit()
+	layer := frame.Layers[canvas.scene.CurrentLayer()]
+	layer.Strokes = append(layer.Strokes, canvas.stroke)
+	if tapered {
+		canvas.invalidate(frame)
+	}
+	if !canvas.stroke.Erase && len(canvas.stroke.Points) > 0 {
+		canvas.colors.use(canvas.stroke.Points[0].Color)
+	}
+	canvas.stroke = nil
+	canvas.pen = nil
+	canvas.stroker = nil
+	canvas.stabilizer = nil
 }
 
-// Clear canvas by using the decaying previous frame
-func (canvas *Canvas) Clear() {
-	if canvas.decay == nil {
-		canvas.Win.Clear(colornames.Black)
-	} else {
-		canv := canvas.Win.Canvas()
-		canv.SetPixels(canvas.decay)
+// Paint draws or erases along the way to the window position `now`
+func (canvas *Canvas) Paint(now pixel.Vec) {
+	if canvas.stroke == nil {
+		canvas.beginStroke()
+	}
+
+	// the mouse has no pressure, how fast it moves stands in for it
+	if elapsed := time.Since(canvas.painted).Seconds(); elapsed > 0 {
+		canvas.pen.Speed(now.Sub(canvas.Win.MousePreviousPosition()).Len() / elapsed)
+	}
+	canvas.painted = time.Now()
+
+	for _, p := range canvas.stabilizer.Add(now.X, now.Y) {
+		canvas.strokeTo(p)
 	}
 }
 
-func (canvas *Canvas) buildFrame() {
-	// clear screen except for canvas
-	canvas.Win.Clear(colornames.Black)
-	canvas.batch = canvas.batches[canvas.curBatch]
-	canvas.batch.Draw(canvas.Win)
-	canvas.Win.Update()
+// strokeTo continues the current stroke to the window position `p`
+func (canvas *Canvas) strokeTo(p brush.Point) {
+	// paint in document pixels, whatever the document is scaled to
+	x, y := canvas.toScene(canvas.fromWindow(pixel.V(p.X, p.Y)))
+	pt, ok := canvas.pen.To(x, y)
+	if !ok {
+		return
+	}
+	canvas.stroke.Points = append(canvas.stroke.Points, pt)
 
-	// now get canvas pixels
-	canv := canvas.Win.Canvas()
-	pixels := canv.Pixels()
+	batch := canvas.batch(canvas.layer(), canvas.stroke)
+	sprite := canvas.tipSprite(canvas.stroke.Tip)
+	for _, d := range canvas.stroker.To(pt) {
+		canvas.drawDab(batch, sprite, canvas.stroke, d)
+	}
+}
 
-	if canvas.curBatch < len(canvas.frames) {
-		canvas.frames[canvas.curBatch] = pixels		
-	} else {
-		// this is so we can dump the frame without GUI as a PNG later
-		canvas.frames = append(canvas.frames, pixels)
+// Clear canvas to the background, showing the onion skin on top unless the animation is playing
+func (canvas *Canvas) Clear() {
+	canvas.drawBackground()
+	if !canvas.playback.Playing {
+		canvas.drawOnion()
 	}
 }
 
 // Dump saves the animation as a set of PNGs using `sceneName` as the naming prefix
-func (canvas *Canvas) Dump(sceneName string) {
-	if _, err := os.Stat(sceneName); os.IsNotExist(err) {
-		os.Mkdir(sceneName, 0700)
+func (canvas *Canvas) Dump(sceneName string) error {
+	if err := os.MkdirAll(sceneName, 0700); err != nil {
+		return err
 	}
 	
-	for i := 0; i < len(canvas.frames); i++ {
-		img := image.NewRGBA(image.Rectangle{image.Point{0, 0}, image.Point{int(canvas.width), int(canvas.height)}})
-		img.Pix = canvas.frames[i]
+	// a PNG per tick, so that held frames play at the right speed
+	frames := export.Held(canvas.animation(), scene.Holds(canvas.scene.Animation()))
+	return export.PNGs(sceneName, sceneName, frames)
+}
 
-		file, err := os.Create(fmt.Sprintf(sceneName + "/%s%06d.png", sceneName, i))
-		if err != nil {
-			panic(err)
-		}
+// DumpGIF saves the animation as an animated GIF named `sceneName`.gif, which loops forever
+// unless `once` and has its colors dithered if `dither`
+func (canvas *Canvas) DumpGIF(sceneName string, once bool, dither bool) error {
+	file, err := os.Create(sceneName + ".gif")
+	if err != nil {
+		return err
+	}
+
+	opts := export.GIFOptions{
+		FPS:    canvas.scene.FPS,
+		Holds:  scene.Holds(canvas.scene.Animation()),
+		Once:   once,
+		Dither: dither,
+	}
+	if err := export.GIF(file, canvas.animation(), opts); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// DumpAPNG saves the animation as a looping lossless animated PNG named `sceneName`.png
+func (canvas *Canvas) DumpAPNG(sceneName string) error {
+	file, err := os.Create(sceneName + ".png")
+	if err != nil {
+		return err
+	}
+
+	opts := export.APNGOptions{
+		FPS:   canvas.scene.FPS,
+		Holds: scene.Holds(canvas.scene.Animation()),
+	}
+	if err := export.APNG(file, canvas.animation(), opts); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// DumpSheet saves the animation as a sprite sheet with a JSON atlas into the directory `sceneName`
+func (canvas *Canvas) DumpSheet(sceneName string) error {
+	if err := os.MkdirAll(sceneName, 0700); err != nil {
+		return err
+	}
 
-		if err := png.Encode(file, img); err != nil {
-			file.Close()
-			panic(err)
+	// sprites are cut out along the content of the frames, which the default background would
+	// cover up, so they are only drawn on a background that was picked
+	frames := canvas.animation()
+	if canvas.scene.Background.IsDefault() {
+		s := *canvas.scene
+		s.Background = scene.Background{}
+		frames = canvas.raster.Frames(&s, s.Animation())
+	}
+
+	opts := export.SheetOptions{
+		Name:    sceneName,
+		FPS:     canvas.scene.FPS,
+		Holds:   scene.Holds(canvas.scene.Animation()),
+		Trim:    true,
+		Padding: 1,
+	}
+	return export.WriteSpriteSheet(sceneName, frames, opts)
+}
+
+// prompt reads a line of keyboard input until ENTER is pressed, showing it behind `label`
+func (canvas *Canvas) prompt(label string) string {
+	// remember previous frame state
+	canv := canvas.Win.Canvas()
+	pixels := canv.Pixels()
+	input := ""
+	canvas.gui.sceneName.WriteString(label)
+	for {
+		canv.SetPixels(pixels)
+		canvas.gui.sceneName.Draw(canvas.Win, pixel.IM)
+		canvas.Win.Update()
+		if canvas.Win.JustPressed(pixelgl.KeyEnter) {
+			break
 		}
+
+		// only read what was typed after the prompt opened, not the key that opened it
+		input = input + canvas.Win.Typed()
+		canvas.gui.sceneName.WriteString(canvas.Win.Typed())
 	}
+	canvas.gui.sceneName.Clear()
+	return input
 }
 
 // Poll user input
 func (canvas *Canvas) Poll() {
-	// paint at mouseclick
-	if canvas.Win.Pressed(pixelgl.MouseButtonLeft) {
+	// drop what was cached for frames that are gone by now
+	defer canvas.prune()
+
+	// pick colors from the color panel or with the eyedropper, go through frames on the timeline
+	// or select strokes, otherwise paint at mouseclick, unless the current layer is hidden or locked
+	picking := canvas.pollColors() || canvas.pollTimeline() || canvas.pollEyedropper() || canvas.selectStrokes()
+	if !picking && canvas.Win.Pressed(pixelgl.MouseButtonLeft) && canvas.editable() {
+		// the frame stays put while it is painted on
+		canvas.pause()
+
+		canvas.beginStroke()
 		for {
-			canvas.Paint(canvas.Win.MousePosition(), canvas.Win.MousePreviousPosition())	
+			canvas.Paint(canvas.Win.MousePosition())
 
 			// draw and poll window inputs
 			canvas.Draw()
 			if canvas.Win.JustReleased(pixelgl.MouseButtonLeft) {
-				// we can CTRL+Z to this snapshot if we want
-				canvas.snapshot()
+				// we can CTRL+Z to before this stroke if we want
+				canvas.endStroke()
 				break
 			}
 			<-canvas.FPS
 		}
 	}
 	
-	// go into erasing mode at keypress E
-	if canvas.Win.JustPressed(pixelgl.KeyE) {
-		if canvas.erasing {
-			canvas.erasing = false
-			canvas.batch.SetColorMask(colornames.White)
+	ctrl := canvas.Win.Pressed(pixelgl.KeyLeftControl) || canvas.Win.Pressed(pixelgl.KeyRightControl)
+	shift := canvas.Win.Pressed(pixelgl.KeyLeftShift) || canvas.Win.Pressed(pixelgl.KeyRightShift)
+
+	// undo at keypress CTRL+Z, redo at keypress CTRL+SHIFT+Z or CTRL+Y
+	if ctrl && (canvas.Win.Repeated(pixelgl.KeyZ) || canvas.Win.JustPressed(pixelgl.KeyZ)) {
+		if shift {
+			canvas.Redo()
 		} else {
-			canvas.erasing = true
-			canvas.batch.SetColorMask(colornames.Black)
+			canvas.Undo()
 		}
 	}
+	if ctrl && (canvas.Win.Repeated(pixelgl.KeyY) || canvas.Win.JustPressed(pixelgl.KeyY)) {
+		canvas.Redo()
+	}
+
+	// go into erasing mode at keypress E
+	if canvas.Win.JustPressed(pixelgl.KeyE) {
+		canvas.erasing = !canvas.erasing
+	}
 		
-	if canvas.Win.JustPressed(pixelgl.KeyLeft) {
-		if canvas.curBatch > 0 {
-			canvas.buildFrame()
-			canvas.curBatch--
-			canvas.batch = canvas.batches[canvas.curBatch]
+	// step to the previous or next frame at keypress left, right, which pauses playing
+	if !shift && !ctrl && canvas.Win.JustPressed(pixelgl.KeyLeft) {
+		canvas.pause()
+		if canvas.scene.Current > 0 {
+			canvas.scene.Current--
 		}
 	}
-	if canvas.Win.JustPressed(pixelgl.KeyRight) {
-		if canvas.curBatch < len(canvas.batches) - 1 {
-			canvas.buildFrame()
-			canvas.curBatch++
-			canvas.batch = canvas.batches[canvas.curBatch]
+	if !shift && !ctrl && canvas.Win.JustPressed(pixelgl.KeyRight) {
+		canvas.pause()
+		if canvas.scene.Current < len(canvas.scene.Frames) - 1 {
+			canvas.scene.Current++
 		}	
 	}
 
-	// save canvas to animation buffer at keypress SPACE
-	if canvas.Win.JustPressed(pixelgl.KeySpace) {
-		canvas.buildFrame()
-
-		// cache batch incase user wants to reuse the previous sketch
-		canvas.batch = pixel.NewBatch(&pixel.TrianglesData{}, canvas.spritesheet)
-		canvas.curBatch = canvas.curBatch + 1
-		canvas.batches = append(canvas.batches, canvas.batch)
-		canvas.snapshots = []pixel.Batch{}
-
-		// as an aid for drawing, indicate the previous frame
-		decay := canvas.Win.Canvas().Pixels()
-		for i := 0; i < len(decay); i++ {
-			decay[i] = uint8(float64(decay[i]) * 0.3)
+	// move the current frame one place earlier or later at keypress CTRL + left, right
+	if ctrl && !shift && canvas.Win.JustPressed(pixelgl.KeyLeft) {
+		if cur := canvas.scene.Current; cur > 0 {
+			canvas.change()
+			canvas.scene.Move(cur, cur-1)
 		}
-
-		canvas.decay = decay
 	}
-
-	// play animation at keypress P
-	if canvas.Win.JustPressed(pixelgl.KeyP) {
-		
-		canv := canvas.Win.Canvas()
-		
-		// show animation at 15 FPS
-		fps15 := time.Tick(time.Second/time.Duration(canvas.playbackFPS))
-		for i := 0; i < len(canvas.frames); i++ {
-			canv.SetPixels(canvas.frames[i])
-			canvas.Win.Update()
-			// note that canvas.Win.Update also calls
-			// canvas.Win.UpdateInput() along with it
-			
-			if canvas.Win.JustPressed(pixelgl.KeyP) {
-				break
-			}
-			<-fps15
+	if ctrl && !shift && canvas.Win.JustPressed(pixelgl.KeyRight) {
+		if cur := canvas.scene.Current; cur < len(canvas.scene.Frames)-1 {
+			canvas.change()
+			canvas.scene.Move(cur, cur+1)
 		}
 	}
 
-	// loop at keypress L
-	if canvas.Win.JustPressed(pixelgl.KeyL) {
+	canvas.pollLayers(shift)
+	canvas.pollBackground(ctrl, shift)
+	canvas.pollBrushes(ctrl)
+	canvas.pollSelection(ctrl, shift)
+	canvas.pollOnion(ctrl, shift)
+	canvas.pollHolds(ctrl, shift)
+	canvas.pollPlayback(ctrl, shift)
 
-		canv := canvas.Win.Canvas()
-		skipped := false
-		
-		for {
-			// show animation at 15 FPS
-			fps15 := time.Tick(time.Second/time.Duration(canvas.playbackFPS))
-			for i := 0; i < len(canvas.frames); i++ {
-				canv.SetPixels(canvas.frames[i])
-
-				canvas.Win.Update()
-				// note that canvas.Win.Update also calls
-				// canvas.Win.UpdateInput() along with it
-
-				if canvas.Win.JustPressed(pixelgl.KeyL) {
-					skipped = true
-					break
-				}	
-				if canvas.Win.Pressed(pixelgl.KeyUp) {
-					canvas.playbackFPS = canvas.playbackFPS + 1
-				}
-				if canvas.Win.Pressed(pixelgl.KeyDown) {
-					canvas.playbackFPS = canvas.playbackFPS - 1
-					if canvas.playbackFPS < 5 {
-						canvas.playbackFPS = 5
-					}
-				}
-				<-fps15
-			}
+	// insert a blank frame after the current one at keypress SPACE, or before it with SHIFT,
+	// and continue drawing on it
+	if canvas.Win.JustPressed(pixelgl.KeySpace) {
+		canvas.change()
 
-			if skipped || canvas.Win.JustPressed(pixelgl.KeyL) {
-				break
-			}	
-			
-			if canvas.Win.Pressed(pixelgl.KeyUp) {
-				canvas.playbackFPS = canvas.playbackFPS + 1
-			}
-			
-			if canvas.Win.Pressed(pixelgl.KeyDown) {
-				canvas.playbackFPS = canvas.playbackFPS - 1
-				if canvas.playbackFPS < 5 {
-					canvas.playbackFPS = 5
-				}
-			}
-			<-canvas.FPS
-		}		
+		// keep the previous frame incase user wants to reuse the previous sketch
+		cur := canvas.scene.Current
+		at := cur + 1
+		if shift {
+			at = cur
+		}
+		canvas.scene.Insert(at, canvas.scene.Frame().Blank())
+		canvas.scene.Current = at
 	}
 
-	// load previous batch at keypress C
-	if canvas.Win.JustPressed(pixelgl.KeyC) {
-		if len(canvas.batches) > 1 {
-			canvas.batches[len(canvas.batches)-1] = canvas.batches[len(canvas.batches)-2]
-			canvas.batch = canvas.batches[len(canvas.batches)-2]
-			canvas.snapshot()
-		} 
+	// duplicate the current frame at keypress CTRL+D, and continue drawing on the copy
+	if ctrl && canvas.Win.JustPressed(pixelgl.KeyD) {
+		canvas.change()
+
+		cur := canvas.scene.Current
+		canvas.scene.Duplicate(cur)
+		canvas.scene.Current = cur + 1
 	}
 
-	if canvas.Win.JustPressed(pixelgl.KeyLeftShift) || canvas.Win.JustPressed(pixelgl.KeyRightShift) {
-		// move canvas to certain directon at keypresses up, down, left, right
-		for {
-			canvas.Win.UpdateInput()
-			if canvas.Win.JustReleased(pixelgl.KeyLeftShift) || canvas.Win.JustReleased(pixelgl.KeyRightShift) {
-				canvas.snapshot()
-				break
-			}
+	// load the previous frame onto the current one at keypress C
+	if canvas.Win.JustPressed(pixelgl.KeyC) {
+		if cur := canvas.scene.Current; cur > 0 {
+			canvas.change()
 
-			if canvas.Win.JustPressed(pixelgl.KeyUp) {
-				// TODO
-				canvas.batch.SetMatrix(pixel.IM.Moved(pixel.V(0, 1)))
-			}
+			// copy, so that painting over it leaves the previous frame as it is
+			canvas.scene.Frames[cur] = canvas.scene.Frames[cur-1].Clone()
+		} 
+	}
 
-			if canvas.Win.JustPressed(pixelgl.KeyDown) {
-				// TODO
-				canvas.batch.SetMatrix(pixel.IM.Moved(pixel.V(0, -1)))	
-			}
+	// move the current frame at keypresses SHIFT + up, down, left, right
+	if shift && !ctrl {
+		// document coordinates point downwards
+		dx, dy := 0, 0
+		if canvas.Win.JustPressed(pixelgl.KeyUp) {
+			dy--
+		}
+		if canvas.Win.JustPressed(pixelgl.KeyDown) {
+			dy++
+		}
+		if canvas.Win.JustPressed(pixelgl.KeyLeft) {
+			dx--
+		}
+		if canvas.Win.JustPressed(pixelgl.KeyRight) {
+			dx++
+		}
 
-			if canvas.Win.JustPressed(pixelgl.KeyLeft) {
-				// TODO
-				canvas.batch.SetMatrix(pixel.IM.Moved(pixel.V(-1, 0)))	
+		if dx != 0 || dy != 0 {
+			// everything moved while holding SHIFT can be undone at once
+			if !canvas.shifting {
+				canvas.change()
+				canvas.shifting = true
 			}
 
-			if canvas.Win.JustPressed(pixelgl.KeyRight) {
-				// TODO
-				canvas.batch.SetMatrix(pixel.IM.Moved(pixel.V(1, 0)))
-			}
-			<-canvas.FPS
+			frame := canvas.edit()
+			frame.Translate(dx, dy)
+			canvas.invalidate(frame)
 		}
 	}
+	if !shift {
+		canvas.shifting = false
+	}
 
 	// reset animation at keypress R
 	if canvas.Win.JustPressed(pixelgl.KeyR) {
-		canvas.frames = [][]uint8{}
-		canvas.batches = []*pixel.Batch{}
-		canvas.curBatch = 0
-		canvas.decay = nil
+		canvas.change()
+		canvas.scene.Frames = []*scene.Frame{scene.NewFrame()}
+		canvas.scene.Current = 0
+		canvas.scene.Layer = 0
+		canvas.resetPlayback()
+		canvas.selection.stroke = nil
 	}
 
 	// delete current frame at keypress D
-	if canvas.Win.JustPressed(pixelgl.KeyD) {
-		if canvas.curBatch < len(canvas.batches)-1 {
-			canvas.batches = append(canvas.batches[:canvas.curBatch], canvas.batches[canvas.curBatch+1:]...)
-			canvas.frames = append(canvas.frames[:canvas.curBatch], canvas.frames[canvas.curBatch+1:]...)
-			canvas.batch = canvas.batches[canvas.curBatch]
+	if !ctrl && canvas.Win.JustPressed(pixelgl.KeyD) {
+		canvas.change()
+
+		// the frame that takes the deleted one's place becomes the current frame
+		cur := canvas.scene.Current
+		if cur < len(canvas.scene.Frames)-1 {
+			canvas.scene.Remove(cur)
 		} else {
-			canvas.batch.Clear()
-		}
-		if len(canvas.batches) == 1 {
-			canvas.decay = nil
-			canvas.frames = [][]uint8{}
+			canvas.scene.Frames[cur] = canvas.scene.Frames[cur].Blank()
 		}
 	}
 
-	// dump animation at keypress ENTER
+	// dump animation at keypress ENTER, every layer on its own with SHIFT
 	if canvas.Win.JustPressed(pixelgl.KeyEnter) {
-		// remember previous frame state
-		canv := canvas.Win.Canvas()
-		pixels := canv.Pixels()
-		sceneName := ""
-		for {
-			sceneName = sceneName + canvas.Win.Typed()
-			canvas.gui.sceneName.WriteString(canvas.Win.Typed())
-			canv.SetPixels(pixels)
-			canvas.gui.sceneName.Draw(canvas.Win, pixel.IM)
-			canvas.Win.Update()
-			if canvas.Win.JustPressed(pixelgl.KeyEnter) {
-				break
+		if name := canvas.prompt(""); name != "" {
+			canvas.scene.Name = name
+		}
+		dump := canvas.Dump
+		if shift {
+			dump = canvas.DumpLayers
+		}
+		if err := dump(canvas.scene.Name); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+
+	// dump animation as a GIF at keypress G, played once instead of looping with SHIFT, and
+	// without dithering with CTRL
+	if canvas.Win.JustPressed(pixelgl.KeyG) {
+		if name := canvas.prompt("GIF "); name != "" {
+			canvas.scene.Name = name
+		}
+		if err := canvas.DumpGIF(canvas.scene.Name, shift, !ctrl); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+
+	// dump animation as an APNG at keypress A
+	if canvas.Win.JustPressed(pixelgl.KeyA) {
+		if name := canvas.prompt("APNG "); name != "" {
+			canvas.scene.Name = name
+		}
+		if err := canvas.DumpAPNG(canvas.scene.Name); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+
+	// dump animation as a sprite sheet at keypress T
+	if !ctrl && canvas.Win.JustPressed(pixelgl.KeyT) {
+		if name := canvas.prompt("Sheet "); name != "" {
+			canvas.scene.Name = name
+		}
+		if err := canvas.DumpSheet(canvas.scene.Name); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+
+	// save project at keypress CTRL+S
+	if ctrl && canvas.Win.JustPressed(pixelgl.KeyS) {
+		if name := canvas.prompt("Save "); name != "" {
+			canvas.scene.Name = strings.TrimSuffix(name, project.Extension)
+		}
+		if err := canvas.Save(project.FileName(canvas.scene.Name)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+
+	// open a palette file at keypress CTRL+P, save the palette at keypress CTRL+SHIFT+P
+	if ctrl && canvas.Win.JustPressed(pixelgl.KeyP) {
+		if shift {
+			if name := canvas.prompt("Save palette "); name != "" {
+				if err := canvas.savePalette(name); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+				}
+			}
+		} else {
+			if name := canvas.prompt("Open palette "); name != "" {
+				if err := canvas.loadPalette(name); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+				}
+			}
+		}
+	}
+
+	// open project at keypress CTRL+O
+	if ctrl && canvas.Win.JustPressed(pixelgl.KeyO) {
+		if name := canvas.prompt("Open "); name != "" {
+			if err := canvas.Load(project.FileName(name)); err != nil {
+				fmt.Fprintln(os.Stderr, err)
 			}
 		}
-		canvas.Dump(sceneName)
 	}
 
 	if canvas.Win.JustPressed(pixelgl.KeyEscape) {
 		canvas.Win.Destroy()
 	}
 
-	// adjust brush size at mousescroll
+	// adjust brush size at mousescroll, and the stabilizer strength at CTRL+mousescroll, or resize
+	// the selected stroke
 	scroll := canvas.Win.MouseScroll()
-	canvas.brushSize = canvas.brushSize - scroll.X + scroll.Y 
-	if canvas.brushSize < 1 {
-		canvas.brushSize = 1
+	if canvas.selection.stroke != nil {
+		canvas.resizeStroke(scroll.Y - scroll.X)
+	} else if ctrl {
+		canvas.smoothing += int(scroll.Y - scroll.X)
+		if canvas.smoothing < 1 {
+			canvas.smoothing = 1
+		}
+		if canvas.smoothing > brush.MaxStrength {
+			canvas.smoothing = brush.MaxStrength
+		}
+	} else {
+		canvas.brushSize = canvas.brushSize - scroll.X + scroll.Y 
+		if canvas.brushSize < 1 {
+			canvas.brushSize = 1
+		}
 	}
 }
 
@@ -475,17 +736,25 @@ func (canvas *Canvas) Poll() {
 func (canvas *Canvas) Draw() {
 	canvas.Clear()
 
-	canvas.batch.Draw(canvas.Win)
+	canvas.drawFrame(canvas.doc, canvas.scene.Current)
+	canvas.fit()
+	canvas.show()
 
 	// update GUI
-	fmt.Fprintf(canvas.gui.brush, "Brush\nSize\t%.0f\nType\t%s", canvas.brushSize, canvas.BrushType())
-	fmt.Fprintf(canvas.gui.frameNr, "Frame Nr. %d/%d", canvas.curBatch+1, len(canvas.batches))
-	fmt.Fprintf(canvas.gui.playbackFPS, "Playback-FPS\t%d", canvas.playbackFPS)
+	fmt.Fprintf(canvas.gui.brush, "Brush\nSize\t%.0f\nType\t%s\nSmooth\t%s\nPressure\t%s\nOnion\t%s", canvas.brushSize, canvas.brushName(), canvas.stabilizerName(), onOff(canvas.pressure), canvas.onionName())
+	fmt.Fprintf(canvas.gui.frameNr, "Frame Nr. %d/%d", canvas.scene.Current+1, len(canvas.scene.Frames))
+	if hold := canvas.scene.Frame().Ticks(); hold > 1 {
+		fmt.Fprintf(canvas.gui.frameNr, "  Hold %d", hold)
+	}
+	fmt.Fprintf(canvas.gui.playbackFPS, "Playback-FPS\t%d  %s  %.1fs", canvas.scene.FPS, canvas.playbackName(), canvas.scene.Duration().Seconds())
+	canvas.writeLayers()
 
 	// draw GUI
-	canvas.brush.Draw(canvas.gui.brushBatch, pixel.IM.Scaled(pixel.ZV, canvas.brushSize/20).Moved(canvas.Win.MousePosition()))
-	canvas.gui.brushBatch.Draw(canvas.Win)
-	canvas.gui.brushBatch.Clear()
+	if !canvas.selection.active {
+		tip := canvas.tipSprite(canvas.brushPreset().Tip)
+		tip.DrawColorMask(canvas.Win, pixel.IM.Scaled(pixel.ZV, tipScale(tip, canvas.zoom()*canvas.brushSize*brushScale)).Moved(canvas.Win.MousePosition()), colornames.Gray)
+	}
+	canvas.drawSelection()
 
 	canvas.gui.brush.Draw(canvas.Win, pixel.IM.Scaled(canvas.gui.brush.Orig, 1.4))
 	canvas.gui.frameNr.Draw(canvas.Win, pixel.IM.Scaled(canvas.gui.frameNr.Orig, 1.4))
@@ -493,6 +762,10 @@ func (canvas *Canvas) Draw() {
 	canvas.gui.frameNr.Clear()
 	canvas.gui.playbackFPS.Draw(canvas.Win, pixel.IM.Scaled(canvas.gui.playbackFPS.Orig, 1.4))
 	canvas.gui.playbackFPS.Clear()
+	canvas.gui.layers.Draw(canvas.Win, pixel.IM.Scaled(canvas.gui.layers.Orig, 1.4))
+	canvas.gui.layers.Clear()
+	canvas.drawColors()
+	canvas.drawTimeline()
 
 	// update window
 	canvas.Win.Update()
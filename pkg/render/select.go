@@ -0,0 +1,259 @@
+package render
+
+import (
+	"math"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+	"golang.org/x/image/colornames"
+
+	"github.com/supermuesli/anim8/pkg/scene"
+)
+
+// hitTolerance is how many window pixels a click may miss a stroke by and still select it
+const hitTolerance = 4.0
+
+// resizeStep is how much a stroke grows per step of the mouse wheel
+const resizeStep = 1.1
+
+// selectState is the stroke picked with the select tool, which edits strokes instead of painting them
+type selectState struct {
+	// active while the select tool is used instead of the brush
+	active bool
+
+	// stroke is the selected stroke, strokes are shared between the copies of a frame, so it is
+	// found again on the current frame by itself, see selected
+	stroke *scene.Stroke
+
+	// dragging while the mouse button held down moves the stroke, and moved once it did
+	dragging bool
+	moved    bool
+
+	// where the stroke was dragged to so far, in document coordinates
+	from pixel.Vec
+}
+
+// selected returns the indices of the layer and of the selected stroke on the current frame,
+// and whether there is one
+func (canvas *Canvas) selected() (int, int, bool) {
+	if canvas.selection.stroke == nil {
+		return 0, 0, false
+	}
+	for l, layer := range canvas.scene.Frame().Layers {
+		for i, s := range layer.Strokes {
+			if s == canvas.selection.stroke {
+				return l, i, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// strokeAt returns the indices of the layer and of the topmost stroke painting at `x`, `y` in
+// document coordinates, and whether there is one. Strokes on hidden and locked layers can't be
+// selected.
+func (canvas *Canvas) strokeAt(x float64, y float64) (int, int, bool) {
+	tolerance := hitTolerance / canvas.zoom()
+
+	layers := canvas.scene.Frame().Layers
+	for l := len(layers) - 1; l >= 0; l-- {
+		if layers[l].Hidden || layers[l].Locked {
+			continue
+		}
+		if i := layers[l].StrokeAt(x, y, tolerance); i >= 0 {
+			return l, i, true
+		}
+	}
+	return 0, 0, false
+}
+
+// editStroke changes the selected stroke on the current frame by calling `edit` with the layer
+// it is on and its index
+func (canvas *Canvas) editStroke(edit func(layer *scene.Layer, i int)) {
+	l, i, ok := canvas.selected()
+	if !ok {
+		return
+	}
+
+	frame := canvas.edit()
+	edit(frame.Layers[l], i)
+	canvas.invalidate(frame)
+}
+
+// replaceStroke puts `s` in place of the selected stroke and selects it
+func (canvas *Canvas) replaceStroke(s *scene.Stroke) {
+	canvas.editStroke(func(layer *scene.Layer, i int) {
+		layer.Strokes[i] = s
+	})
+	canvas.selection.stroke = s
+}
+
+// moveStroke moves the selected stroke to index `to` in the painting order of its layer, limited
+// to the strokes there
+func (canvas *Canvas) moveStroke(to func(i int, n int) int) {
+	l, i, ok := canvas.selected()
+	if !ok {
+		return
+	}
+
+	n := len(canvas.scene.Frame().Layers[l].Strokes)
+	j := to(i, n)
+	if j < 0 {
+		j = 0
+	}
+	if j > n-1 {
+		j = n - 1
+	}
+	if j == i {
+		return
+	}
+
+	canvas.change()
+	canvas.editStroke(func(layer *scene.Layer, i int) {
+		layer.MoveStroke(i, j)
+	})
+}
+
+// resizeStroke grows the selected stroke by `steps` steps of the mouse wheel, shrinking it when
+// negative
+func (canvas *Canvas) resizeStroke(steps float64) {
+	if _, _, ok := canvas.selected(); !ok || steps == 0 {
+		return
+	}
+
+	canvas.change()
+	canvas.replaceStroke(canvas.selection.stroke.Scaled(math.Pow(resizeStep, steps)))
+}
+
+// pollSelection handles the keys of the select tool
+func (canvas *Canvas) pollSelection(ctrl bool, shift bool) {
+	win := canvas.Win
+	sel := canvas.selection
+
+	// switch between painting and selecting strokes at keypress S
+	if !ctrl && win.JustPressed(pixelgl.KeyS) {
+		sel.active = !sel.active
+		sel.stroke = nil
+	}
+
+	// the stroke is gone after undoing or going to another frame
+	if _, _, ok := canvas.selected(); !ok {
+		sel.stroke = nil
+		return
+	}
+
+	// delete the selected stroke at keypress DELETE or BACKSPACE, SHIFT+DELETE deletes the layer
+	if win.JustPressed(pixelgl.KeyBackspace) || !shift && win.JustPressed(pixelgl.KeyDelete) {
+		canvas.change()
+		canvas.editStroke(func(layer *scene.Layer, i int) {
+			layer.RemoveStroke(i)
+		})
+		sel.stroke = nil
+		return
+	}
+
+	// paint the selected stroke with the current color at keypress F
+	if win.JustPressed(pixelgl.KeyF) && !sel.stroke.Erase {
+		canvas.change()
+		canvas.replaceStroke(sel.stroke.Recolored(canvas.colors.foreground))
+		canvas.colors.use(canvas.colors.foreground)
+	}
+
+	// paint the selected stroke above or below the next one at keypress UP, DOWN, and above or
+	// below all others on its layer at keypress END, HOME
+	if !shift && (win.JustPressed(pixelgl.KeyUp) || win.Repeated(pixelgl.KeyUp)) {
+		canvas.moveStroke(func(i int, n int) int { return i + 1 })
+	}
+	if !shift && (win.JustPressed(pixelgl.KeyDown) || win.Repeated(pixelgl.KeyDown)) {
+		canvas.moveStroke(func(i int, n int) int { return i - 1 })
+	}
+	if win.JustPressed(pixelgl.KeyEnd) {
+		canvas.moveStroke(func(i int, n int) int { return n - 1 })
+	}
+	if win.JustPressed(pixelgl.KeyHome) {
+		canvas.moveStroke(func(i int, n int) int { return 0 })
+	}
+}
+
+// selectStrokes selects strokes at mouseclick and drags them along while the select tool is
+// used, and tells whether the mouse is busy doing so, rather than painting
+func (canvas *Canvas) selectStrokes() bool {
+	win := canvas.Win
+	sel := canvas.selection
+	if !sel.active {
+		return false
+	}
+	if !win.Pressed(pixelgl.MouseButtonLeft) {
+		sel.dragging = false
+		return false
+	}
+
+	x, y := canvas.toScene(canvas.fromWindow(win.MousePosition()))
+
+	// select the topmost stroke under the mouse on any layer, and make its layer the current one
+	if win.JustPressed(pixelgl.MouseButtonLeft) {
+		sel.stroke = nil
+		sel.dragging = false
+		if l, i, ok := canvas.strokeAt(x, y); ok {
+			canvas.scene.Layer = l
+			sel.stroke = canvas.scene.Frame().Layers[l].Strokes[i]
+			sel.dragging = true
+			sel.moved = false
+			sel.from = pixel.V(x, y)
+		}
+		return true
+	}
+
+	if _, _, ok := canvas.selected(); !ok || !sel.dragging {
+		return true
+	}
+
+	dx, dy := x-sel.from.X, y-sel.from.Y
+	if dx == 0 && dy == 0 {
+		return true
+	}
+
+	// the whole drag can be undone at once
+	if !sel.moved {
+		canvas.change()
+		sel.moved = true
+	}
+	canvas.replaceStroke(sel.stroke.Translated(dx, dy))
+	sel.from = pixel.V(x, y)
+
+	return true
+}
+
+// drawSelection outlines the selected stroke along its path and its bounds
+func (canvas *Canvas) drawSelection() {
+	imd := canvas.gui.selection
+	imd.Clear()
+
+	if _, _, ok := canvas.selected(); ok {
+		window := func(x float64, y float64) pixel.Vec {
+			return canvas.view.Project(canvas.fromScene(x, y))
+		}
+
+		imd.Color = colornames.Deepskyblue
+		for _, p := range canvas.selection.stroke.Points {
+			imd.Push(window(p.X, p.Y))
+		}
+		imd.Line(1)
+
+		r := canvas.selection.stroke.Bounds()
+		imd.Push(window(float64(r.Min.X), float64(r.Min.Y)), window(float64(r.Max.X), float64(r.Max.Y)))
+		imd.Rectangle(1)
+	}
+
+	// a crosshair stands in for the brush
+	if canvas.selection.active {
+		mouse := canvas.Win.MousePosition()
+		imd.Color = colornames.Gray
+		imd.Push(mouse.Sub(pixel.V(8, 0)), mouse.Add(pixel.V(8, 0)))
+		imd.Line(1)
+		imd.Push(mouse.Sub(pixel.V(0, 8)), mouse.Add(pixel.V(0, 8)))
+		imd.Line(1)
+	}
+
+	imd.Draw(canvas.Win)
+}
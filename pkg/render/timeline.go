@@ -0,0 +1,324 @@
+package render
+
+import (
+	"math"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/pixelgl"
+	"golang.org/x/image/colornames"
+)
+
+// the timeline runs along the bottom of the window, the document is fit above it
+const (
+	timelineLeft   = 250.0
+	timelineBottom = 40.0
+	thumbHeight    = 64.0
+	thumbGap       = 4.0
+	scrubHeight    = 10.0
+
+	// timelineTop is where the document starts above the timeline
+	timelineTop = timelineBottom + thumbHeight + thumbGap + scrubHeight + 12
+)
+
+// maxHoldMarks is how many marks at most show how long a frame is held on the timeline
+const maxHoldMarks = 10
+
+// what a click on the timeline started to do
+const (
+	timelineNone = iota
+	timelineIdle
+	timelineMove
+	timelineScrub
+)
+
+// timelineState is the strip of frame thumbnails to navigate and reorder frames with
+type timelineState struct {
+	// first is the index of the leftmost frame shown, the strip scrolls to keep the current one in view
+	first int
+
+	// what the mouse button currently held down does, see timelineNone
+	dragging int
+
+	// from is the index of the frame being dragged to another place
+	from int
+
+	// frames are drawn in here at the size of the document before they are scaled down
+	buffer *pixelgl.Canvas
+	imd    *imdraw.IMDraw
+}
+
+// newTimelineState prepares the timeline for a document of the size `bounds`
+func newTimelineState(bounds pixel.Rect) *timelineState {
+	buffer := pixelgl.NewCanvas(bounds)
+	buffer.SetSmooth(true)
+	return &timelineState{buffer: buffer, imd: imdraw.New(nil)}
+}
+
+// timelinePanel is where the parts of the timeline are in the window
+type timelinePanel struct {
+	bounds pixel.Rect
+	scrub  pixel.Rect
+
+	// the thumbnails of the frames shown, from the frame at index `first` on
+	thumbs []pixel.Rect
+	first  int
+
+	// the distance from one thumbnail to the next
+	step float64
+}
+
+// timelinePanel lays out the timeline along the bottom of the window, scrolled so that the
+// current frame is in view
+func (canvas *Canvas) timelinePanel() timelinePanel {
+	right := canvas.width - 30
+	doc := canvas.doc.Bounds()
+	w := math.Max(16, math.Min(256, thumbHeight*doc.W()/doc.H()))
+
+	var panel timelinePanel
+	panel.step = w + thumbGap
+	slots := int(math.Max(1, math.Floor((right-timelineLeft+thumbGap)/panel.step)))
+
+	// scroll as little as it takes
+	tl := canvas.timeline
+	cur, n := canvas.scene.Current, len(canvas.scene.Frames)
+	if cur < tl.first {
+		tl.first = cur
+	}
+	if cur >= tl.first+slots {
+		tl.first = cur - slots + 1
+	}
+	tl.first = clampInt(tl.first, 0, int(math.Max(0, float64(n-slots))))
+	panel.first = tl.first
+
+	for i := panel.first; i < n && i < panel.first+slots; i++ {
+		x := timelineLeft + float64(i-panel.first)*panel.step
+		panel.thumbs = append(panel.thumbs, pixel.R(x, timelineBottom, x+w, timelineBottom+thumbHeight))
+	}
+
+	end := timelineLeft + float64(slots)*panel.step - thumbGap
+	top := timelineBottom + thumbHeight + thumbGap
+	panel.scrub = pixel.R(timelineLeft, top, end, top+scrubHeight)
+	panel.bounds = pixel.R(timelineLeft, timelineBottom, end, top+scrubHeight)
+	return panel
+}
+
+// index returns the index of the frame at the window position `x` along the timeline, limited
+// to the frames there are
+func (panel timelinePanel) index(canvas *Canvas, x float64) int {
+	i := panel.first + int(math.Floor((x-timelineLeft)/panel.step))
+	return clampInt(i, 0, len(canvas.scene.Frames)-1)
+}
+
+// thumbnail returns the i-th frame scaled down to the size of `r`, drawing it if it isn't
+// cached yet
+func (canvas *Canvas) thumbnail(i int, r pixel.Rect) *pixelgl.Canvas {
+	c := canvas.cached(canvas.scene.Frames[i])
+	if c.thumb != nil && c.thumb.Bounds().Size() == r.Size() {
+		return c.thumb
+	}
+
+	buffer := canvas.timeline.buffer
+	buffer.Clear(pixel.Alpha(0))
+	canvas.drawFrame(buffer, i)
+
+	c.thumb = pixelgl.NewCanvas(pixel.R(0, 0, r.W(), r.H()))
+	c.thumb.SetSmooth(true)
+	doc := buffer.Bounds()
+	buffer.Draw(c.thumb, pixel.IM.Scaled(pixel.ZV, r.W()/doc.W()).Moved(c.thumb.Bounds().Center()))
+	return c.thumb
+}
+
+// setHold shows the frames at the indices `frames` for `hold` ticks each, at least 1, and tells
+// whether that changed any of them. The change is recorded for undo only if it did, all at once.
+func (canvas *Canvas) setHold(hold int, frames ...int) bool {
+	if hold < 1 {
+		hold = 1
+	}
+
+	var changed []int
+	for _, i := range frames {
+		if canvas.scene.Frames[i].Ticks() != hold {
+			changed = append(changed, i)
+		}
+	}
+	if len(changed) == 0 {
+		return false
+	}
+
+	canvas.change()
+	for _, i := range changed {
+		// the frame looks the same, so what is cached for it still holds
+		c, ok := canvas.cache[canvas.scene.Frames[i]]
+		delete(canvas.cache, canvas.scene.Frames[i])
+		frame := canvas.scene.Edit(i)
+		frame.Hold = hold
+		if ok {
+			canvas.cache[frame] = c
+		}
+	}
+	return true
+}
+
+// pollHolds handles the keys that set how long frames are held
+func (canvas *Canvas) pollHolds(ctrl bool, shift bool) {
+	win := canvas.Win
+	cur := canvas.scene.Current
+
+	// hold the current frame one tick longer at keypress W (wait), one tick shorter with SHIFT
+	if !ctrl && (win.JustPressed(pixelgl.KeyW) || win.Repeated(pixelgl.KeyW)) {
+		hold := canvas.scene.Frame().Ticks() + 1
+		if shift {
+			hold -= 2
+		}
+		canvas.setHold(hold, cur)
+	}
+
+	// hold the current frame for 1 to 9 ticks at keypress CTRL + 1 to 9, and every frame with
+	// SHIFT, e.g. to shoot the whole animation on twos
+	for i, key := range presetKeys {
+		if !ctrl || !win.JustPressed(key) {
+			continue
+		}
+
+		if !shift {
+			canvas.setHold(i+1, cur)
+			continue
+		}
+		all := make([]int, len(canvas.scene.Frames))
+		for j := range all {
+			all[j] = j
+		}
+		canvas.setHold(i+1, all...)
+	}
+}
+
+// pollTimeline jumps to frames clicked on the timeline, moves frames dragged along it and
+// scrubs through the frames while the bar above it is dragged. It tells whether the mouse is
+// busy doing so, rather than painting.
+func (canvas *Canvas) pollTimeline() bool {
+	win := canvas.Win
+	tl := canvas.timeline
+	mouse := win.MousePosition()
+	panel := canvas.timelinePanel()
+
+	if !win.Pressed(pixelgl.MouseButtonLeft) {
+		// drop the dragged frame where the mouse button was released
+		if tl.dragging == timelineMove {
+			if to := panel.index(canvas, mouse.X); to != tl.from {
+				canvas.change()
+				canvas.scene.Move(tl.from, to)
+			}
+		}
+		tl.dragging = timelineNone
+		return false
+	}
+
+	if win.JustPressed(pixelgl.MouseButtonLeft) {
+		switch {
+		case panel.scrub.Contains(mouse):
+			canvas.pause()
+			tl.dragging = timelineScrub
+		case panel.bounds.Contains(mouse):
+			tl.dragging = timelineIdle
+			for i, r := range panel.thumbs {
+				if r.Contains(mouse) {
+					canvas.pause()
+					canvas.scene.Current = panel.first + i
+					tl.dragging = timelineMove
+					tl.from = panel.first + i
+				}
+			}
+		}
+	}
+
+	// keep scrubbing while the mouse is dragged, even beyond the timeline
+	if tl.dragging == timelineScrub {
+		canvas.scene.Current = panel.index(canvas, mouse.X)
+	}
+
+	return tl.dragging != timelineNone
+}
+
+// drawTimeline draws the thumbnails of the frames into the window, the current one highlighted
+func (canvas *Canvas) drawTimeline() {
+	tl := canvas.timeline
+	panel := canvas.timelinePanel()
+	imd := tl.imd
+
+	// the background the frames are drawn on, transparency shows as gray
+	imd.Clear()
+	imd.Color = canvas.scene.Background.Color
+	if canvas.scene.Background.Color.A < 255 {
+		imd.Color = colornames.Lightgray
+	}
+	for _, r := range panel.thumbs {
+		imd.Push(r.Min, r.Max)
+		imd.Rectangle(0)
+	}
+	imd.Color = colornames.Darkgray
+	imd.Push(panel.scrub.Min, panel.scrub.Max)
+	imd.Rectangle(0)
+
+	// the frames within the play range stand out
+	in, out := canvas.playRange()
+	imd.Color = colornames.Gray
+	for i, r := range panel.thumbs {
+		if j := panel.first + i; j >= in && j <= out {
+			imd.Push(pixel.V(r.Min.X-thumbGap/2, panel.scrub.Min.Y), pixel.V(r.Max.X+thumbGap/2, panel.scrub.Max.Y))
+			imd.Rectangle(0)
+		}
+	}
+	imd.Draw(canvas.Win)
+
+	for i, r := range panel.thumbs {
+		canvas.thumbnail(panel.first+i, r).Draw(canvas.Win, pixel.IM.Moved(r.Center()))
+	}
+
+	imd.Clear()
+	for i, r := range panel.thumbs {
+		imd.Color = colornames.Gray
+		if panel.first+i == canvas.scene.Current {
+			imd.Color = colornames.Red
+		}
+		imd.Push(r.Min, r.Max)
+		imd.Rectangle(2)
+	}
+
+	// held frames are marked once per tick
+	imd.Color = colornames.White
+	for i, r := range panel.thumbs {
+		hold := canvas.scene.Frames[panel.first+i].Ticks()
+		if hold < 2 {
+			continue
+		}
+		for t := 0; t < hold && t < maxHoldMarks; t++ {
+			min := r.Min.Add(pixel.V(4+float64(t)*6, 4))
+			imd.Push(min, min.Add(pixel.V(3, 3)))
+			imd.Rectangle(0)
+		}
+	}
+
+	// the playhead over the current frame
+	if cur := canvas.scene.Current - panel.first; cur >= 0 && cur < len(panel.thumbs) {
+		imd.Color = colornames.Red
+		imd.Push(pixel.V(panel.thumbs[cur].Min.X, panel.scrub.Min.Y), pixel.V(panel.thumbs[cur].Max.X, panel.scrub.Max.Y))
+		imd.Rectangle(0)
+	}
+
+	// where the dragged frame is dropped, on the side it moves to
+	if tl.dragging == timelineMove {
+		to := panel.index(canvas, canvas.Win.MousePosition().X)
+		if k := to - panel.first; to != tl.from && k >= 0 && k < len(panel.thumbs) {
+			x := panel.thumbs[k].Min.X - thumbGap/2
+			if to > tl.from {
+				x = panel.thumbs[k].Max.X + thumbGap/2
+			}
+			imd.Color = colornames.White
+			imd.Push(pixel.V(x, timelineBottom-4), pixel.V(x, timelineBottom+thumbHeight+4))
+			imd.Line(2)
+		}
+	}
+
+	imd.Draw(canvas.Win)
+}
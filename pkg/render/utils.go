@@ -2,19 +2,15 @@ package render
 
 import (
 	"image/png"
+	"image"
 	"bytes"
 
-	"github.com/faiface/pixel"
 	"golang.org/x/image/font"
 	"github.com/golang/freetype/truetype"
 )
 
-func loadPicture(data []byte) (pixel.Picture, error) {
-	img, err := png.Decode(bytes.NewReader(data))
-	if err != nil {
-		return nil, err
-	}
-	return pixel.PictureDataFromImage(img), nil
+func loadImage(data []byte) (image.Image, error) {
+	return png.Decode(bytes.NewReader(data))
 }
 
 func loadTTF(data []byte, size float64) (font.Face, error) {
@@ -0,0 +1,59 @@
+package scene
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+)
+
+// Background is what every frame of a scene is drawn on
+type Background struct {
+	// Color fills the frames, a transparent color leaves them transparent
+	Color color.RGBA
+
+	// Image is stretched over the whole frame on top of the color, if there is one
+	Image *image.RGBA
+}
+
+// DefaultBackground is the background of new scenes
+var DefaultBackground = Background{Color: color.RGBA{0, 0, 0, 255}}
+
+// IsDefault tells whether the background is still the one new scenes start out with, rather
+// than one that was picked
+func (b Background) IsDefault() bool {
+	return b.Image == nil && b.Color == DefaultBackground.Color
+}
+
+// ParseColor parses a color written as #rrggbb or #rrggbbaa, or the word transparent
+func ParseColor(s string) (color.RGBA, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "transparent" {
+		return color.RGBA{}, nil
+	}
+
+	var c color.NRGBA
+	c.A = 255
+
+	hex := strings.TrimPrefix(s, "#")
+	var err error
+	switch len(hex) {
+	case 6:
+		_, err = fmt.Sscanf(hex, "%02x%02x%02x", &c.R, &c.G, &c.B)
+	case 8:
+		_, err = fmt.Sscanf(hex, "%02x%02x%02x%02x", &c.R, &c.G, &c.B, &c.A)
+	default:
+		err = fmt.Errorf("expected #rrggbb or #rrggbbaa")
+	}
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color %q: %v", s, err)
+	}
+
+	return color.RGBAModel.Convert(c).(color.RGBA), nil
+}
+
+// FormatColor writes `c` as #rrggbbaa, the counterpart of ParseColor
+func FormatColor(c color.RGBA) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("#%02x%02x%02x%02x", n.R, n.G, n.B, n.A)
+}
@@ -0,0 +1,172 @@
+package scene
+
+import (
+	"image"
+	"image/color"
+)
+
+// Frame is a single drawing of the animation, made of layers from bottom to top
+type Frame struct {
+	Layers []*Layer
+
+	// Hold is how many ticks of 1/FPS seconds the frame is shown for, e.g. 2 for shooting on
+	// twos. 0 counts as 1, see Ticks.
+	Hold int
+
+	// frozen frames are recorded in a History and must not change anymore, see Scene.Edit
+	frozen bool
+}
+
+// Layer holds the strokes painted onto a frame, on top of an optional image
+type Layer struct {
+	Name string
+
+	// Hidden layers are neither shown nor exported
+	Hidden bool
+
+	// Opacity the layer is composited with, from 0 to 1
+	Opacity float64
+
+	// Locked layers can't be painted on
+	Locked bool
+
+	// Image is raster content the layer started out with, e.g. from an older project file
+	Image *image.RGBA
+
+	// Offset of the image in the frame
+	Offset image.Point
+
+	// Strokes in painting order
+	Strokes []*Stroke
+}
+
+// Stroke is everything painted from pressing until releasing the mouse button, recorded as the
+// path the brush took. It is rendered from that path, at any resolution.
+type Stroke struct {
+	// Erase strokes paint with the eraser
+	Erase bool
+
+	// Brush is the name of the brush preset the stroke is painted with, see brush.Presets
+	Brush string
+
+	// Tip is the name of the brush tip image, "" is the default tip, see Scene.Tips
+	Tip string
+
+	// Opacity the stroke as a whole is painted or erased with, from 0 to 1
+	Opacity float64
+
+	// Seed makes the jitter of the brush come out the same every time the stroke is rendered
+	Seed int64
+
+	// Points along the path in painting order
+	Points []Point
+}
+
+// Point is a position on the path of a stroke, along with how the brush paints there. The brush
+// changes gradually from one point to the next.
+type Point struct {
+	X float64
+	Y float64
+
+	// Width of the brush in pixels
+	Width float64
+
+	// Alpha the brush paints with, from 0 to 1
+	Alpha float64
+
+	// Color the brush paints with, erase strokes don't have one
+	Color color.RGBA
+}
+
+// NewFrame creates an empty frame with a single layer
+func NewFrame() *Frame {
+	return &Frame{
+		Layers: []*Layer{NewLayer("Layer 1")},
+	}
+}
+
+// NewLayer creates an empty, visible and opaque layer
+func NewLayer(name string) *Layer {
+	return &Layer{Name: name, Opacity: 1}
+}
+
+// Clone copies the frame so that changing the copy leaves `f` as it is. Strokes are never
+// modified in place, so they are shared.
+func (f *Frame) Clone() *Frame {
+	c := &Frame{Layers: make([]*Layer, len(f.Layers)), Hold: f.Hold}
+	for i, l := range f.Layers {
+		c.Layers[i] = l.Clone()
+	}
+	return c
+}
+
+// Blank returns a frame with the same layers and hold as `f`, but nothing painted on them
+func (f *Frame) Blank() *Frame {
+	b := &Frame{Layers: make([]*Layer, len(f.Layers)), Hold: f.Hold}
+	for i, l := range f.Layers {
+		b.Layers[i] = &Layer{Name: l.Name, Hidden: l.Hidden, Opacity: l.Opacity, Locked: l.Locked}
+	}
+	return b
+}
+
+// Ticks returns how many ticks of 1/FPS seconds the frame is shown for, at least 1
+func (f *Frame) Ticks() int {
+	if f.Hold < 1 {
+		return 1
+	}
+	return f.Hold
+}
+
+// Holds returns the ticks every one of `frames` is shown for, see Ticks
+func Holds(frames []*Frame) []int {
+	holds := make([]int, len(frames))
+	for i, f := range frames {
+		holds[i] = f.Ticks()
+	}
+	return holds
+}
+
+// Empty tells whether nothing was painted onto the frame
+func (f *Frame) Empty() bool {
+	for _, l := range f.Layers {
+		if l.Image != nil || len(l.Strokes) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Translate moves everything on the frame by `dx`, `dy` pixels
+func (f *Frame) Translate(dx int, dy int) {
+	for _, l := range f.Layers {
+		l.Translate(dx, dy)
+	}
+}
+
+// Clone copies the layer, sharing the image and the strokes
+func (l *Layer) Clone() *Layer {
+	c := *l
+	c.Strokes = append([]*Stroke(nil), l.Strokes...)
+	return &c
+}
+
+// Translate moves everything on the layer by `dx`, `dy` pixels
+func (l *Layer) Translate(dx int, dy int) {
+	l.Offset = l.Offset.Add(image.Pt(dx, dy))
+
+	for i, s := range l.Strokes {
+		l.Strokes[i] = s.Translated(float64(dx), float64(dy))
+	}
+}
+
+// Translated returns a copy of the stroke moved by `dx`, `dy`
+func (s *Stroke) Translated(dx float64, dy float64) *Stroke {
+	c := *s
+	c.Points = make([]Point, len(s.Points))
+	for i, p := range s.Points {
+		p.X += dx
+		p.Y += dy
+		c.Points[i] = p
+	}
+	return &c
+}
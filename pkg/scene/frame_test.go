@@ -0,0 +1,46 @@
+package scene
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFrameTicks(t *testing.T) {
+	tests := []struct {
+		hold int
+		want int
+	}{
+		// frames that aren't held are shown for a single tick
+		{0, 1},
+		{-2, 1},
+		{1, 1},
+		{4, 4},
+	}
+
+	for _, test := range tests {
+		f := NewFrame()
+		f.Hold = test.hold
+		if got := f.Ticks(); got != test.want {
+			t.Errorf("frame held %d is shown for %d ticks, want %d", test.hold, got, test.want)
+		}
+	}
+}
+
+func TestHolds(t *testing.T) {
+	s := numbered(3, 0)
+	s.Frames[0].Hold = 0
+	if got, want := Holds(s.Frames), []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("holds are %v, want %v", got, want)
+	}
+
+	// blank frames and copies keep the hold of the frame they are made from
+	if s.Frames[2].Blank().Hold != 3 || s.Frames[2].Clone().Hold != 3 {
+		t.Error("a blank frame or copy lost its hold")
+	}
+
+	s.FPS = 10
+	if got, want := time.Duration(Holds(s.Frames)[2])*s.FrameDuration(), 300*time.Millisecond; got != want {
+		t.Errorf("the last frame is shown for %v, want %v", got, want)
+	}
+}
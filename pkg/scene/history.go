@@ -0,0 +1,92 @@
+package scene
+
+// DefaultHistoryLimit is how many changes a new History can undo
+const DefaultHistoryLimit = 100
+
+// History keeps earlier states of a scene around so that changes can be undone and redone.
+//
+// Recording a state freezes its frames, Scene.Edit then copies a frame before it is changed, so
+// that a state only costs as much memory as the frames that changed since.
+type History struct {
+	// Limit is how many states are kept, the oldest ones are dropped first
+	Limit int
+
+	undo []state
+	redo []state
+}
+
+// state is the frame order along with the current frame
+type state struct {
+	frames  []*Frame
+	current int
+}
+
+// NewHistory creates an empty History that keeps up to `limit` states
+func NewHistory(limit int) *History {
+	return &History{Limit: limit}
+}
+
+// Push records the state of `s` before it gets changed, which discards everything that could be redone
+func (h *History) Push(s *Scene) {
+	h.undo = append(h.undo, s.state())
+	if h.Limit > 0 && len(h.undo) > h.Limit {
+		h.undo = append(h.undo[:0], h.undo[len(h.undo)-h.Limit:]...)
+	}
+	h.redo = nil
+}
+
+// Undo restores the state of `s` before the last change and tells whether there was one
+func (h *History) Undo(s *Scene) bool {
+	if len(h.undo) == 0 {
+		return false
+	}
+
+	h.redo = append(h.redo, s.state())
+	s.restore(h.undo[len(h.undo)-1])
+	h.undo = h.undo[:len(h.undo)-1]
+	return true
+}
+
+// Redo restores the state of `s` the last Undo went back from and tells whether there was one
+func (h *History) Redo(s *Scene) bool {
+	if len(h.redo) == 0 {
+		return false
+	}
+
+	h.undo = append(h.undo, s.state())
+	s.restore(h.redo[len(h.redo)-1])
+	h.redo = h.redo[:len(h.redo)-1]
+	return true
+}
+
+// Len returns how many changes can be undone and redone
+func (h *History) Len() (int, int) {
+	return len(h.undo), len(h.redo)
+}
+
+// Clear forgets every recorded state
+func (h *History) Clear() {
+	h.undo = nil
+	h.redo = nil
+}
+
+// Edit returns the i-th frame, ready to be changed. Frames recorded by a History are replaced
+// by a copy first, so the frame returned may differ from the one that was at index `i`.
+func (s *Scene) Edit(i int) *Frame {
+	if s.Frames[i].frozen {
+		s.Frames[i] = s.Frames[i].Clone()
+	}
+	return s.Frames[i]
+}
+
+func (s *Scene) state() state {
+	for _, f := range s.Frames {
+		f.frozen = true
+	}
+	return state{append([]*Frame(nil), s.Frames...), s.Current}
+}
+
+func (s *Scene) restore(st state) {
+	s.Frames = append([]*Frame(nil), st.frames...)
+	s.Current = st.current
+}
@@ -0,0 +1,95 @@
+package scene
+
+import "testing"
+
+// paint adds a stroke to the current frame the way the editor does, recording the state before
+func paint(h *History, s *Scene) *Stroke {
+	h.Push(s)
+	stroke := &Stroke{Opacity: 1, Points: []Point{{X: 1, Y: 1, Width: 1, Alpha: 1}}}
+	f := s.Edit(s.Current)
+	f.Layers[0].Strokes = append(f.Layers[0].Strokes, stroke)
+	return stroke
+}
+
+func strokes(s *Scene) int {
+	return len(s.Frame().Layers[0].Strokes)
+}
+
+func TestHistoryUndoRedo(t *testing.T) {
+	s := New("test", 8, 8)
+	h := NewHistory(DefaultHistoryLimit)
+
+	paint(h, s)
+	before := s.Frames[0]
+	paint(h, s)
+	if s.Frames[0] == before {
+		t.Fatal("a recorded frame was changed in place")
+	}
+
+	if !h.Undo(s) || strokes(s) != 1 {
+		t.Fatalf("undo left %d strokes, want 1", strokes(s))
+	}
+	if !h.Undo(s) || strokes(s) != 0 {
+		t.Fatalf("undo left %d strokes, want 0", strokes(s))
+	}
+	if h.Undo(s) {
+		t.Error("undid more changes than were made")
+	}
+
+	if !h.Redo(s) || !h.Redo(s) || strokes(s) != 2 {
+		t.Fatalf("redo left %d strokes, want 2", strokes(s))
+	}
+	if h.Redo(s) {
+		t.Error("redid more changes than were undone")
+	}
+
+	// a new change discards what could be redone
+	h.Undo(s)
+	paint(h, s)
+	if undo, redo := h.Len(); undo != 2 || redo != 0 {
+		t.Errorf("can undo %d and redo %d changes, want 2 and 0", undo, redo)
+	}
+}
+
+func TestHistoryFrames(t *testing.T) {
+	s := New("test", 8, 8)
+	h := NewHistory(DefaultHistoryLimit)
+
+	h.Push(s)
+	s.Insert(1, s.Frame().Blank())
+	s.Current = 1
+	s.Edit(1).Hold = 3
+
+	h.Undo(s)
+	if len(s.Frames) != 1 || s.Current != 0 {
+		t.Fatalf("undo left %d frames on frame %d, want 1 on frame 0", len(s.Frames), s.Current)
+	}
+	h.Redo(s)
+	if len(s.Frames) != 2 || s.Current != 1 || s.Frame().Ticks() != 3 {
+		t.Fatalf("redo left %d frames on frame %d held %d", len(s.Frames), s.Current, s.Frame().Ticks())
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	s := New("test", 8, 8)
+	h := NewHistory(3)
+
+	for i := 0; i < 5; i++ {
+		paint(h, s)
+	}
+	if undo, _ := h.Len(); undo != 3 {
+		t.Fatalf("can undo %d changes, want 3", undo)
+	}
+
+	// the oldest changes are the ones dropped
+	for h.Undo(s) {
+	}
+	if strokes(s) != 2 {
+		t.Errorf("undoing everything left %d strokes, want 2", strokes(s))
+	}
+
+	h.Clear()
+	if undo, redo := h.Len(); undo != 0 || redo != 0 {
+		t.Errorf("can undo %d and redo %d changes after clearing", undo, redo)
+	}
+}
@@ -0,0 +1,85 @@
+package scene
+
+import (
+	"fmt"
+)
+
+// The layers of a scene are kept in the same order on every frame, so that e.g. the line art is
+// the same layer throughout the animation. The functions below change them on all frames at once.
+
+// Layers returns how many layers the frames of the scene have at most
+func (s *Scene) Layers() int {
+	n := 0
+	for _, f := range s.Frames {
+		if len(f.Layers) > n {
+			n = len(f.Layers)
+		}
+	}
+	return n
+}
+
+// AddLayer inserts an empty layer at index `i` into every frame and returns its name
+func (s *Scene) AddLayer(i int) string {
+	name := fmt.Sprintf("Layer %d", s.Layers()+1)
+
+	for j := range s.Frames {
+		f := s.Edit(j)
+
+		k := i
+		if k > len(f.Layers) {
+			k = len(f.Layers)
+		}
+		f.Layers = append(f.Layers, nil)
+		copy(f.Layers[k+1:], f.Layers[k:])
+		f.Layers[k] = NewLayer(name)
+	}
+
+	return name
+}
+
+// RemoveLayer deletes the layer at index `i` from every frame, frames keep at least one layer
+func (s *Scene) RemoveLayer(i int) {
+	for j := range s.Frames {
+		if i >= len(s.Frames[j].Layers) {
+			continue
+		}
+
+		f := s.Edit(j)
+		if len(f.Layers) == 1 {
+			f.Layers[0] = NewLayer(f.Layers[0].Name)
+			continue
+		}
+		f.Layers = append(f.Layers[:i], f.Layers[i+1:]...)
+	}
+}
+
+// MoveLayer moves the layer at index `from` to index `to` in every frame that has both
+func (s *Scene) MoveLayer(from int, to int) {
+	if from == to {
+		return
+	}
+
+	for j := range s.Frames {
+		if from >= len(s.Frames[j].Layers) || to >= len(s.Frames[j].Layers) {
+			continue
+		}
+
+		f := s.Edit(j)
+		l := f.Layers[from]
+		if from < to {
+			copy(f.Layers[from:to], f.Layers[from+1:to+1])
+		} else {
+			copy(f.Layers[to+1:from+1], f.Layers[to:from])
+		}
+		f.Layers[to] = l
+	}
+}
+
+// SetLayer calls `set` with the layer at index `i` of every frame that has it, to change its settings
+func (s *Scene) SetLayer(i int, set func(l *Layer)) {
+	for j := range s.Frames {
+		if i < len(s.Frames[j].Layers) {
+			set(s.Edit(j).Layers[i])
+		}
+	}
+}
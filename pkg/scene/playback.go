@@ -0,0 +1,148 @@
+package scene
+
+import "time"
+
+// what playback does at the end of the play range
+const (
+	PlayOnce = iota
+	PlayLoop
+	PlayPingPong
+)
+
+// Playback plays the animation of a scene, its current frame is the playhead. Frames are timed
+// by the time that passed, so they are shown as long as they are held however often the
+// playhead is moved along.
+type Playback struct {
+	Playing bool
+
+	// Mode is what happens at the end of the play range, see PlayOnce
+	Mode int
+
+	// Reverse plays backwards, ping-pong turns it around at either end
+	Reverse bool
+
+	// In and Out are the first and last frame played, -1 for the first and last frame of the animation
+	In  int
+	Out int
+
+	// how long the current frame was shown so far
+	elapsed time.Duration
+}
+
+// NewPlayback loops over the whole animation
+func NewPlayback() *Playback {
+	return &Playback{Mode: PlayLoop, In: -1, Out: -1}
+}
+
+// Range returns the first and last frame played, limited to the frames of the animation of `s`
+func (p *Playback) Range(s *Scene) (int, int) {
+	n := len(s.Animation())
+
+	in, out := p.In, p.Out
+	if in < 0 || in >= n {
+		in = 0
+	}
+	if out < 0 || out >= n {
+		out = n - 1
+	}
+	if in > out {
+		in, out = 0, n-1
+	}
+	return in, out
+}
+
+// Play starts playing from the current frame, or from the start of the play range if the
+// current frame is outside of it or at its end
+func (p *Playback) Play(s *Scene) {
+	in, out := p.Range(s)
+	cur := s.Current
+
+	start, end := in, out
+	if p.Reverse {
+		start, end = out, in
+	}
+	if cur < in || cur > out || p.Mode == PlayOnce && cur == end {
+		s.Current = start
+	}
+
+	p.Playing = true
+	p.elapsed = 0
+}
+
+// Pause stops playing at the current frame
+func (p *Playback) Pause() {
+	p.Playing = false
+}
+
+// Stop stops playing and goes back to the start of the play range
+func (p *Playback) Stop(s *Scene) {
+	p.Pause()
+
+	in, out := p.Range(s)
+	s.Current = in
+	if p.Reverse {
+		s.Current = out
+	}
+}
+
+// Reset stops playing and plays the whole animation again, for when the frames are replaced by
+// others that the play range doesn't fit
+func (p *Playback) Reset() {
+	p.Pause()
+	p.In, p.Out = -1, -1
+}
+
+// Advance moves the playhead of `s` along by `d`, the time that passed since it was last moved
+func (p *Playback) Advance(s *Scene, d time.Duration) {
+	if !p.Playing {
+		return
+	}
+	p.elapsed += d
+
+	frames := s.Animation()
+	for p.Playing {
+		cur := s.Current
+		if cur >= len(frames) {
+			p.Play(s)
+			return
+		}
+
+		hold := time.Duration(frames[cur].Ticks()) * s.FrameDuration()
+		if p.elapsed < hold {
+			return
+		}
+		p.elapsed -= hold
+		s.Current = p.next(s, cur)
+	}
+}
+
+// next returns the frame to show after the frame `cur`, and stops playing at the end of the
+// play range if the animation is played once
+func (p *Playback) next(s *Scene, cur int) int {
+	in, out := p.Range(s)
+
+	step := 1
+	if p.Reverse {
+		step = -1
+	}
+	if next := cur + step; next >= in && next <= out {
+		return next
+	}
+
+	switch p.Mode {
+	case PlayLoop:
+		if p.Reverse {
+			return out
+		}
+		return in
+	case PlayPingPong:
+		p.Reverse = !p.Reverse
+		if next := cur - step; next >= in && next <= out {
+			return next
+		}
+		return cur
+	}
+
+	p.Playing = false
+	return cur
+}
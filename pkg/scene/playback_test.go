@@ -0,0 +1,175 @@
+package scene
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// tick is how long a tick lasts in the scenes of held
+const tick = 100 * time.Millisecond
+
+// held returns a scene at 10 FPS with a painted frame for every hold in `holds`, followed by the
+// empty frame for the next drawing
+func held(holds ...int) *Scene {
+	s := New("test", 8, 8)
+	s.FPS = 10
+	h := NewHistory(DefaultHistoryLimit)
+	for i, hold := range holds {
+		if i > 0 {
+			s.Insert(i, NewFrame())
+			s.Current = i
+		}
+		paint(h, s)
+		s.Frame().Hold = hold
+	}
+	s.Insert(len(holds), NewFrame())
+	s.Current = 0
+	return s
+}
+
+func TestPlaybackAdvance(t *testing.T) {
+	tests := []struct {
+		name    string
+		holds   []int
+		mode    int
+		reverse bool
+		in, out int
+		start   int
+		steps   int
+		want    []int
+		playing bool
+	}{
+		{"loop", []int{1, 1, 1}, PlayLoop, false, -1, -1, 0, 5, []int{1, 2, 0, 1, 2}, true},
+		{"holds", []int{2, 1, 3}, PlayLoop, false, -1, -1, 0, 7, []int{0, 1, 2, 2, 2, 0, 0}, true},
+		{"once", []int{1, 1, 1}, PlayOnce, false, -1, -1, 0, 4, []int{1, 2, 2, 2}, false},
+		{"ping-pong", []int{1, 1, 1}, PlayPingPong, false, -1, -1, 0, 6, []int{1, 2, 1, 0, 1, 2}, true},
+		{"ping-pong held", []int{1, 2, 1}, PlayPingPong, false, -1, -1, 0, 6, []int{1, 1, 2, 1, 1, 0}, true},
+		{"reverse loop", []int{1, 1, 1}, PlayLoop, true, -1, -1, 2, 4, []int{1, 0, 2, 1}, true},
+		{"reverse once", []int{1, 1, 1}, PlayOnce, true, -1, -1, 2, 3, []int{1, 0, 0}, false},
+		{"range", []int{1, 1, 1, 1, 1}, PlayLoop, false, 1, 3, 0, 4, []int{2, 3, 1, 2}, true},
+		{"range once", []int{1, 1, 1, 1, 1}, PlayOnce, false, 1, 2, 4, 3, []int{2, 2, 2}, false},
+		{"range ping-pong", []int{1, 1, 1, 1, 1}, PlayPingPong, true, 1, 3, 3, 5, []int{2, 1, 2, 3, 2}, true},
+		{"single frame ping-pong", []int{1, 1, 1}, PlayPingPong, false, 1, 1, 1, 2, []int{1, 1}, true},
+	}
+
+	for _, test := range tests {
+		s := held(test.holds...)
+		s.Current = test.start
+		p := NewPlayback()
+		p.Mode, p.Reverse, p.In, p.Out = test.mode, test.reverse, test.in, test.out
+
+		p.Play(s)
+		var got []int
+		for i := 0; i < test.steps; i++ {
+			p.Advance(s, tick)
+			got = append(got, s.Current)
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: played frames %v, want %v", test.name, got, test.want)
+		}
+		if p.Playing != test.playing {
+			t.Errorf("%s: playing is %v, want %v", test.name, p.Playing, test.playing)
+		}
+	}
+}
+
+func TestPlaybackTiming(t *testing.T) {
+	s := held(1, 3, 1)
+	p := NewPlayback()
+	p.Play(s)
+
+	// time adds up however it is split
+	p.Advance(s, tick/2)
+	p.Advance(s, tick/2)
+	if s.Current != 1 {
+		t.Fatalf("after a tick the frame is %d, want 1", s.Current)
+	}
+	p.Advance(s, 3*tick+tick/2)
+	if s.Current != 2 {
+		t.Fatalf("after 4.5 ticks the frame is %d, want 2", s.Current)
+	}
+
+	// several frames can pass at once
+	p.Advance(s, 2*tick)
+	if s.Current != 1 {
+		t.Errorf("after 6.5 ticks the frame is %d, want 1", s.Current)
+	}
+
+	// nothing moves while paused
+	p.Pause()
+	p.Advance(s, 10*tick)
+	if s.Current != 1 {
+		t.Errorf("paused playback moved to frame %d", s.Current)
+	}
+}
+
+func TestPlaybackPlay(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    int
+		reverse bool
+		in, out int
+		start   int
+		want    int
+	}{
+		{"from the current frame", PlayLoop, false, -1, -1, 2, 2},
+		{"from the start of the range", PlayLoop, false, 1, 2, 0, 1},
+		{"from the end of the range backwards", PlayLoop, true, 1, 2, 3, 2},
+		{"again after playing once", PlayOnce, false, -1, -1, 3, 0},
+		{"again after playing once backwards", PlayOnce, true, -1, -1, 0, 3},
+		{"on from the end of a loop", PlayLoop, false, -1, -1, 3, 3},
+		{"past the animation", PlayLoop, false, -1, -1, 4, 0},
+	}
+
+	for _, test := range tests {
+		s := held(1, 1, 1, 1)
+		s.Current = test.start
+		p := NewPlayback()
+		p.Mode, p.Reverse, p.In, p.Out = test.mode, test.reverse, test.in, test.out
+
+		p.Play(s)
+		if !p.Playing || s.Current != test.want {
+			t.Errorf("%s: playing %v from frame %d, want %d", test.name, p.Playing, s.Current, test.want)
+		}
+	}
+}
+
+func TestPlaybackRange(t *testing.T) {
+	tests := []struct {
+		in, out         int
+		wantIn, wantOut int
+	}{
+		{-1, -1, 0, 3},
+		{1, 2, 1, 2},
+		{2, -1, 2, 3},
+		// the trailing empty frame isn't part of the animation
+		{1, 4, 1, 3},
+		{5, 2, 0, 2},
+		{3, 1, 0, 3},
+	}
+
+	s := held(1, 1, 1, 1)
+	for _, test := range tests {
+		p := NewPlayback()
+		p.In, p.Out = test.in, test.out
+		if in, out := p.Range(s); in != test.wantIn || out != test.wantOut {
+			t.Errorf("range %d to %d plays %d to %d, want %d to %d", test.in, test.out, in, out, test.wantIn, test.wantOut)
+		}
+	}
+
+	p := NewPlayback()
+	p.In, p.Out = 1, 2
+	s.Current = 3
+	p.Play(s)
+	p.Stop(s)
+	if p.Playing || s.Current != 1 {
+		t.Errorf("stopping left playing %v on frame %d, want the start of the range", p.Playing, s.Current)
+	}
+
+	p.Reset()
+	if in, out := p.Range(s); in != 0 || out != 3 {
+		t.Errorf("reset plays %d to %d, want everything", in, out)
+	}
+}
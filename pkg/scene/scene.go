@@ -0,0 +1,147 @@
+package scene
+
+import (
+	"image"
+	"time"
+)
+
+// DefaultFPS is the playback speed of new scenes
+const DefaultFPS = 15
+
+// Scene is an animation as a plain document, independent of any window. The GUI edits it and
+// the exporters render it.
+//
+// Coordinates are in document pixels with the origin in the top left corner.
+type Scene struct {
+	Name   string
+	Width  int
+	Height int
+
+	// FPS is the playback speed, every frame is shown for 1/FPS seconds times its hold, see Frame.Hold
+	FPS int
+
+	// Background the frames are drawn on
+	Background Background
+
+	// Frames in playback order
+	Frames []*Frame
+
+	// Current is the index of the frame being edited
+	Current int
+
+	// Layer is the index of the layer being edited, see Layers
+	Layer int
+
+	// Tips are the brush tip images strokes are painted with by name, besides the default tip
+	Tips map[string]*image.RGBA
+}
+
+// New creates a scene with a single empty frame
+func New(name string, width int, height int) *Scene {
+	return &Scene{
+		Name:       name,
+		Width:      width,
+		Height:     height,
+		FPS:        DefaultFPS,
+		Background: DefaultBackground,
+		Frames:     []*Frame{NewFrame()},
+		Tips:       make(map[string]*image.RGBA),
+	}
+}
+
+// Frame returns the frame being edited
+func (s *Scene) Frame() *Frame {
+	return s.Frames[s.Current]
+}
+
+// CurrentLayer returns the index of the layer being edited, limited to the layers of the current frame
+func (s *Scene) CurrentLayer() int {
+	if n := len(s.Frame().Layers); s.Layer >= n {
+		return n - 1
+	}
+	if s.Layer < 0 {
+		return 0
+	}
+	return s.Layer
+}
+
+// Animation returns the frames that make up the animation. As long as nothing is drawn on the
+// last frame, it is the next drawing rather than part of the animation.
+func (s *Scene) Animation() []*Frame {
+	n := len(s.Frames)
+	if n > 1 && s.Frames[n-1].Empty() {
+		n--
+	}
+	return s.Frames[:n]
+}
+
+// Insert puts `f` at index `i`, shifting the following frames back
+func (s *Scene) Insert(i int, f *Frame) {
+	s.Frames = append(s.Frames, nil)
+	copy(s.Frames[i+1:], s.Frames[i:])
+	s.Frames[i] = f
+
+	if s.Current >= i && len(s.Frames) > 1 {
+		s.Current++
+	}
+}
+
+// Remove deletes the frame at index `i`. The last remaining frame is cleared instead.
+func (s *Scene) Remove(i int) {
+	if len(s.Frames) == 1 {
+		s.Frames[0] = NewFrame()
+		return
+	}
+
+	s.Frames = append(s.Frames[:i], s.Frames[i+1:]...)
+
+	if s.Current > i || s.Current == len(s.Frames) {
+		s.Current--
+	}
+}
+
+// Duplicate inserts a copy of the frame at index `i` right after it
+func (s *Scene) Duplicate(i int) {
+	s.Insert(i+1, s.Frames[i].Clone())
+}
+
+// Move moves the frame at index `from` to index `to`, the current frame moves along if it is the one
+func (s *Scene) Move(from int, to int) {
+	if from == to {
+		return
+	}
+
+	f := s.Frames[from]
+	if from < to {
+		copy(s.Frames[from:to], s.Frames[from+1:to+1])
+	} else {
+		copy(s.Frames[to+1:from+1], s.Frames[to:from])
+	}
+	s.Frames[to] = f
+
+	switch {
+	case s.Current == from:
+		s.Current = to
+	case from < s.Current && s.Current <= to:
+		s.Current--
+	case to <= s.Current && s.Current < from:
+		s.Current++
+	}
+}
+
+// FrameDuration is how long a single tick lasts, which frames are shown for one or more of
+func (s *Scene) FrameDuration() time.Duration {
+	if s.FPS < 1 {
+		return time.Second
+	}
+	return time.Second / time.Duration(s.FPS)
+}
+
+// Duration is how long the whole animation plays, see Animation
+func (s *Scene) Duration() time.Duration {
+	ticks := 0
+	for _, f := range s.Animation() {
+		ticks += f.Ticks()
+	}
+	return time.Duration(ticks) * s.FrameDuration()
+}
@@ -0,0 +1,135 @@
+package scene
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// numbered returns a scene of `n` frames, each held as long as its index plus one so that the
+// frames can be told apart, with `current` being edited
+func numbered(n int, current int) *Scene {
+	s := New("test", 8, 8)
+	s.Frames = nil
+	for i := 0; i < n; i++ {
+		f := NewFrame()
+		f.Hold = i + 1
+		s.Frames = append(s.Frames, f)
+	}
+	s.Current = current
+	return s
+}
+
+// order returns the frames of `s` by the index they had in numbered, -1 for new ones
+func order(s *Scene, frames []*Frame) []int {
+	var indices []int
+	for _, f := range s.Frames {
+		index := -1
+		for i, g := range frames {
+			if f == g {
+				index = i
+			}
+		}
+		indices = append(indices, index)
+	}
+	return indices
+}
+
+func TestSceneOperations(t *testing.T) {
+	tests := []struct {
+		name    string
+		frames  int
+		current int
+		op      func(s *Scene)
+		want    []int
+		wantCur int
+	}{
+		{"insert after current", 3, 1, func(s *Scene) { s.Insert(2, NewFrame()) }, []int{0, 1, -1, 2}, 1},
+		{"insert before current", 3, 1, func(s *Scene) { s.Insert(1, NewFrame()) }, []int{0, -1, 1, 2}, 2},
+		{"insert at end", 2, 1, func(s *Scene) { s.Insert(2, NewFrame()) }, []int{0, 1, -1}, 1},
+		{"remove current", 3, 1, func(s *Scene) { s.Remove(1) }, []int{0, 2}, 1},
+		{"remove before current", 3, 2, func(s *Scene) { s.Remove(0) }, []int{1, 2}, 1},
+		{"remove after current", 3, 0, func(s *Scene) { s.Remove(2) }, []int{0, 1}, 0},
+		{"remove current last frame", 3, 2, func(s *Scene) { s.Remove(2) }, []int{0, 1}, 1},
+		{"remove last remaining frame", 1, 0, func(s *Scene) { s.Remove(0) }, []int{-1}, 0},
+		{"move current later", 4, 1, func(s *Scene) { s.Move(1, 3) }, []int{0, 2, 3, 1}, 3},
+		{"move current earlier", 4, 2, func(s *Scene) { s.Move(2, 0) }, []int{2, 0, 1, 3}, 0},
+		{"move over current forward", 4, 1, func(s *Scene) { s.Move(0, 2) }, []int{1, 2, 0, 3}, 0},
+		{"move over current backward", 4, 1, func(s *Scene) { s.Move(3, 0) }, []int{3, 0, 1, 2}, 2},
+		{"move elsewhere", 4, 0, func(s *Scene) { s.Move(2, 3) }, []int{0, 1, 3, 2}, 0},
+		{"move in place", 3, 1, func(s *Scene) { s.Move(1, 1) }, []int{0, 1, 2}, 1},
+		{"duplicate current", 3, 1, func(s *Scene) { s.Duplicate(1) }, []int{0, 1, -1, 2}, 1},
+		{"duplicate before current", 3, 2, func(s *Scene) { s.Duplicate(0) }, []int{0, -1, 1, 2}, 3},
+	}
+
+	for _, test := range tests {
+		s := numbered(test.frames, test.current)
+		frames := append([]*Frame(nil), s.Frames...)
+		current := s.Frame()
+
+		test.op(s)
+		if got := order(s, frames); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: frames are %v, want %v", test.name, got, test.want)
+		}
+		if s.Current != test.wantCur {
+			t.Errorf("%s: current frame is %d, want %d", test.name, s.Current, test.wantCur)
+		}
+
+		// the frame being edited stays the current one, unless it was removed
+		if s.Current < 0 || s.Current >= len(s.Frames) {
+			t.Fatalf("%s: current frame %d is out of %d frames", test.name, s.Current, len(s.Frames))
+		}
+		if contains(s.Frames, current) && s.Frame() != current {
+			t.Errorf("%s: the current frame moved from under the editor", test.name)
+		}
+	}
+}
+
+func TestSceneDuplicate(t *testing.T) {
+	s := numbered(2, 0)
+	paint(NewHistory(DefaultHistoryLimit), s)
+	s.Duplicate(0)
+
+	orig, dup := s.Frames[0], s.Frames[1]
+	if dup == orig || dup.Hold != orig.Hold || len(dup.Layers[0].Strokes) != 1 {
+		t.Fatalf("duplicate is not a copy of the frame")
+	}
+
+	// painting on the copy leaves the original as it is
+	dup.Layers[0].Strokes = append(dup.Layers[0].Strokes, &Stroke{})
+	if len(orig.Layers[0].Strokes) != 1 {
+		t.Error("painting on the duplicate changed the original")
+	}
+}
+
+func TestSceneRemoveLast(t *testing.T) {
+	s := New("test", 8, 8)
+	paint(NewHistory(DefaultHistoryLimit), s)
+	s.Remove(0)
+
+	if len(s.Frames) != 1 || !s.Frame().Empty() || s.Current != 0 {
+		t.Errorf("removing the last frame left %d frames, empty %v", len(s.Frames), s.Frame().Empty())
+	}
+}
+
+func TestSceneDuration(t *testing.T) {
+	s := held(1, 3, 2)
+	if got, want := s.Duration(), 600*time.Millisecond; got != want {
+		t.Errorf("animation plays for %v, want %v", got, want)
+	}
+
+	// the empty frame for the next drawing isn't played
+	s.Frames[len(s.Frames)-1].Hold = 5
+	if got, want := s.Duration(), 600*time.Millisecond; got != want {
+		t.Errorf("animation with a held empty frame plays for %v, want %v", got, want)
+	}
+}
+
+func contains(frames []*Frame, f *Frame) bool {
+	for _, g := range frames {
+		if g == f {
+			return true
+		}
+	}
+	return false
+}
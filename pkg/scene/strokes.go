@@ -0,0 +1,119 @@
+package scene
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Strokes are never changed in place, since frames recorded in a History share them. The
+// functions below return changed copies instead, to put in place of the original.
+
+// Hit tells whether the path of the stroke passes `x`, `y` within its width, give or take
+// `tolerance` pixels
+func (s *Stroke) Hit(x float64, y float64, tolerance float64) bool {
+	for i, p := range s.Points {
+		q := p
+		if i+1 < len(s.Points) {
+			q = s.Points[i+1]
+		} else if i > 0 {
+			continue
+		}
+
+		// the point closest to `x`, `y` on the segment from `p` to `q`
+		dx, dy := q.X-p.X, q.Y-p.Y
+		t := 0.0
+		if l := dx*dx + dy*dy; l > 0 {
+			t = math.Max(0, math.Min(1, ((x-p.X)*dx+(y-p.Y)*dy)/l))
+		}
+
+		width := p.Width + (q.Width-p.Width)*t
+		if math.Hypot(p.X+dx*t-x, p.Y+dy*t-y) <= width/2+tolerance {
+			return true
+		}
+	}
+	return false
+}
+
+// Bounds returns the pixels the path of the stroke covers along with its width
+func (s *Stroke) Bounds() image.Rectangle {
+	var r image.Rectangle
+	for _, p := range s.Points {
+		w := p.Width / 2
+		r = r.Union(image.Rect(
+			int(math.Floor(p.X-w)),
+			int(math.Floor(p.Y-w)),
+			int(math.Ceil(p.X+w)),
+			int(math.Ceil(p.Y+w)),
+		))
+	}
+	return r
+}
+
+// Scaled returns a copy of the stroke grown by `factor` around the center of its path, the
+// width of the brush along with it
+func (s *Stroke) Scaled(factor float64) *Stroke {
+	if len(s.Points) == 0 {
+		return s
+	}
+
+	minX, minY := s.Points[0].X, s.Points[0].Y
+	maxX, maxY := minX, minY
+	for _, p := range s.Points {
+		minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+		minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+	}
+	cx, cy := (minX+maxX)/2, (minY+maxY)/2
+
+	c := *s
+	c.Points = make([]Point, len(s.Points))
+	for i, p := range s.Points {
+		p.X = cx + (p.X-cx)*factor
+		p.Y = cy + (p.Y-cy)*factor
+		p.Width *= factor
+		c.Points[i] = p
+	}
+	return &c
+}
+
+// Recolored returns a copy of the stroke painted with `col` all along
+func (s *Stroke) Recolored(col color.RGBA) *Stroke {
+	c := *s
+	c.Points = make([]Point, len(s.Points))
+	for i, p := range s.Points {
+		p.Color = col
+		c.Points[i] = p
+	}
+	return &c
+}
+
+// StrokeAt returns the index of the topmost stroke of the layer that paints at `x`, `y`, give or
+// take `tolerance` pixels, -1 if there is none. Erase strokes leave nothing to hit.
+func (l *Layer) StrokeAt(x float64, y float64, tolerance float64) int {
+	for i := len(l.Strokes) - 1; i >= 0; i-- {
+		if !l.Strokes[i].Erase && l.Strokes[i].Hit(x, y, tolerance) {
+			return i
+		}
+	}
+	return -1
+}
+
+// MoveStroke moves the stroke at index `from` to index `to`, painting it above the strokes below `to`
+func (l *Layer) MoveStroke(from int, to int) {
+	if from == to {
+		return
+	}
+
+	s := l.Strokes[from]
+	if from < to {
+		copy(l.Strokes[from:to], l.Strokes[from+1:to+1])
+	} else {
+		copy(l.Strokes[to+1:from+1], l.Strokes[to:from])
+	}
+	l.Strokes[to] = s
+}
+
+// RemoveStroke deletes the stroke at index `i`
+func (l *Layer) RemoveStroke(i int) {
+	l.Strokes = append(l.Strokes[:i], l.Strokes[i+1:]...)
+}
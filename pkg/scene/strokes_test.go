@@ -0,0 +1,118 @@
+package scene
+
+import (
+	"image"
+	"testing"
+)
+
+// segment returns a stroke from `x0`, `y0` to `x1`, `y1`, `width0` across at its start and
+// `width1` at its end
+func segment(x0 float64, y0 float64, width0 float64, x1 float64, y1 float64, width1 float64) *Stroke {
+	return &Stroke{Opacity: 1, Points: []Point{
+		{X: x0, Y: y0, Width: width0, Alpha: 1},
+		{X: x1, Y: y1, Width: width1, Alpha: 1},
+	}}
+}
+
+func TestStrokeHit(t *testing.T) {
+	line := segment(10, 10, 4, 30, 10, 4)
+	wedge := segment(10, 10, 2, 30, 10, 10)
+	dot := &Stroke{Points: []Point{{X: 5, Y: 5, Width: 2}}}
+
+	tests := []struct {
+		name      string
+		stroke    *Stroke
+		x, y      float64
+		tolerance float64
+		want      bool
+	}{
+		{"on the path", line, 20, 10, 0, true},
+		{"within the width", line, 20, 12, 0, true},
+		{"beside the width", line, 20, 13, 0, false},
+		{"within the tolerance", line, 20, 13, 1, true},
+		{"beyond the tolerance", line, 20, 15.5, 3, false},
+		{"round end", line, 31.5, 11, 0, true},
+		{"past the end", line, 33, 10, 0, false},
+		{"thin end of a wedge", wedge, 11, 13, 0, false},
+		{"wide end of a wedge", wedge, 29, 13, 0, true},
+		{"single point", dot, 5, 6, 0, true},
+		{"beside a single point", dot, 5, 7, 0.5, false},
+		{"no points", &Stroke{}, 0, 0, 10, false},
+	}
+
+	for _, test := range tests {
+		if got := test.stroke.Hit(test.x, test.y, test.tolerance); got != test.want {
+			t.Errorf("%s: hit at %v,%v give or take %v is %v, want %v", test.name, test.x, test.y, test.tolerance, got, test.want)
+		}
+	}
+}
+
+func TestStrokeAt(t *testing.T) {
+	erase := segment(0, 10, 4, 40, 10, 4)
+	erase.Erase = true
+
+	l := NewLayer("test")
+	l.Strokes = []*Stroke{
+		segment(0, 10, 4, 40, 10, 4),
+		segment(20, 0, 4, 20, 40, 4),
+		erase,
+	}
+
+	tests := []struct {
+		x, y float64
+		want int
+	}{
+		// the crossing goes to the topmost stroke, erase strokes can't be hit
+		{20, 10, 1},
+		{5, 10, 0},
+		{20, 35, 1},
+		{35, 35, -1},
+	}
+	for _, test := range tests {
+		if got := l.StrokeAt(test.x, test.y, 1); got != test.want {
+			t.Errorf("stroke at %v,%v is %d, want %d", test.x, test.y, got, test.want)
+		}
+	}
+}
+
+func TestStrokeTransforms(t *testing.T) {
+	s := segment(10, 20, 2, 30, 40, 4)
+
+	tests := []struct {
+		name   string
+		stroke *Stroke
+		want   []Point
+		bounds image.Rectangle
+	}{
+		{"scaled up", s.Scaled(2), []Point{{X: 0, Y: 10, Width: 4}, {X: 40, Y: 50, Width: 8}}, image.Rect(-2, 8, 44, 54)},
+		{"scaled down", s.Scaled(0.5), []Point{{X: 15, Y: 25, Width: 1}, {X: 25, Y: 35, Width: 2}}, image.Rect(14, 24, 26, 36)},
+		{"translated", s.Translated(-5, 2.5), []Point{{X: 5, Y: 22.5, Width: 2}, {X: 25, Y: 42.5, Width: 4}}, image.Rect(4, 21, 27, 45)},
+		{"translated back", s.Translated(3, 3).Translated(-3, -3), s.Points, s.Bounds()},
+	}
+
+	for _, test := range tests {
+		if len(test.stroke.Points) != len(test.want) {
+			t.Fatalf("%s: got %d points, want %d", test.name, len(test.stroke.Points), len(test.want))
+		}
+		for i, p := range test.stroke.Points {
+			want := test.want[i]
+			if p.X != want.X || p.Y != want.Y || p.Width != want.Width {
+				t.Errorf("%s: point %d is %v,%v %v across, want %v,%v %v across", test.name, i, p.X, p.Y, p.Width, want.X, want.Y, want.Width)
+			}
+			if p.Alpha != 1 {
+				t.Errorf("%s: point %d lost its alpha", test.name, i)
+			}
+		}
+		if b := test.stroke.Bounds(); b != test.bounds {
+			t.Errorf("%s: bounds are %v, want %v", test.name, b, test.bounds)
+		}
+	}
+
+	// the original is left as it is
+	if s.Points[0].X != 10 || s.Points[1].Width != 4 {
+		t.Errorf("transforming changed the original stroke to %v", s.Points)
+	}
+	if empty := (&Stroke{}).Scaled(2); len(empty.Points) != 0 {
+		t.Errorf("scaling a stroke without points gave %v", empty.Points)
+	}
+}